@@ -0,0 +1,106 @@
+package mask
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode/utf8"
+)
+
+// rareKey identifies a value tagged with MaskTypeRare for counting.
+type rareKey struct {
+	typ   reflect.Type
+	value string
+}
+
+// SetRareValueThreshold sets the number of times a value must be seen in a batch not to be masked by the "rare" mask
+// from default masker.
+func SetRareValueThreshold(n int) {
+	defaultMasker.SetRareValueThreshold(n)
+}
+
+// MaskBatch masks each of the targets, counting the values tagged with mask:"rare" across all of them
+// from default masker.
+func MaskBatch[T any](targets []T) ([]T, error) {
+	return MaskBatchWith(defaultMasker, targets)
+}
+
+// SetRareValueThreshold sets the number of times a value must be seen in a batch not to be masked by the "rare" mask.
+// The values of the same type tagged with mask:"rare" that are seen fewer than n times across the batch masked by MaskBatch are masked,
+// as they could be used to identify a record. The "rare" mask only works in MaskBatch, and keeps the values as they are in Mask.
+// default 2 (the values seen only once are masked)
+func (m *Masker) SetRareValueThreshold(n int) {
+	m.rareThreshold = n
+}
+
+// MaskBatchWith masks each of the targets with the masker, counting the values tagged with mask:"rare" across all of them.
+// A rare string is masked with the mask character for each character, and a rare value of another type is set to its zero value.
+// The targets are walked twice, once to count the values and once to mask them, so the mask funcs may be called twice for each value.
+// In ErrorModeCollect, the paths of the errors start with the index of the target, like "[1].Name".
+func MaskBatchWith[T any](m *Masker, targets []T) ([]T, error) {
+	counts := make(map[rareKey]int)
+	for _, target := range targets {
+		if any(target) == nil {
+			continue
+		}
+		s := m.newMaskState()
+		s.rareCounts = counts
+		s.countingRare = true
+		if _, err := m.mask(s, reflect.ValueOf(target), "", reflect.Value{}); err != nil {
+			return nil, err
+		}
+	}
+
+	ret := make([]T, len(targets))
+	var errs FieldErrors
+	for i, target := range targets {
+		// a nil interface has nothing to mask
+		if any(target) == nil {
+			continue
+		}
+		s := m.newMaskState()
+		s.rareCounts = counts
+		rv, err := m.mask(s, reflect.ValueOf(target), "", reflect.Value{})
+		if err != nil {
+			return nil, err
+		}
+		masked, ok := rv.Interface().(T)
+		if !ok {
+			return nil, fmt.Errorf("mask: masked value of type %s cannot be converted to %T", rv.Type(), masked)
+		}
+		ret[i] = masked
+		for _, err := range s.errs {
+			path := fmt.Sprintf("[%d]", i)
+			if err.Path != "" && !strings.HasPrefix(err.Path, "[") {
+				path += "."
+			}
+			errs = append(errs, &FieldError{Path: path + err.Path, Err: err.Err})
+		}
+	}
+	if len(errs) > 0 {
+		return ret, errs
+	}
+
+	return ret, nil
+}
+
+// maskRare counts the value while counting the values of a batch, and masks it if it is rare in the batch.
+func (m *Masker) maskRare(s *maskState, rv reflect.Value, mp reflect.Value) (reflect.Value, error) {
+	key := rareKey{typ: rv.Type(), value: fmt.Sprint(rv.Interface())}
+	masked := rv
+	if s.countingRare {
+		s.rareCounts[key]++
+	} else if s.rareCounts[key] < m.rareThreshold {
+		if rv.Kind() == reflect.String {
+			masked = reflect.ValueOf(strings.Repeat(m.maskCharFor(MaskTypeRare), utf8.RuneCountInString(rv.String()))).Convert(rv.Type())
+		} else {
+			masked = reflect.Zero(rv.Type())
+		}
+	}
+	if mp.IsValid() {
+		mp.Set(masked)
+		return mp, nil
+	}
+
+	return masked, nil
+}
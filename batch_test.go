@@ -0,0 +1,110 @@
+package mask
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaskBatch(t *testing.T) {
+	type patient struct {
+		Name    string `mask:"filled"`
+		ZipCode string `mask:"rare"`
+		Age     int    `mask:"rare"`
+		Disease string
+	}
+
+	input := []patient{
+		{Name: "ヤハッ！", ZipCode: "123-4567", Age: 30, Disease: "cold"},
+		{Name: "ハァ？", ZipCode: "123-4567", Age: 30, Disease: "flu"},
+		{Name: "ウラ", ZipCode: "123-4567", Age: 41, Disease: "cold"},
+		{Name: "フゥン", ZipCode: "999-0001", Age: 41, Disease: "flu"},
+	}
+
+	tests := map[string]struct {
+		threshold int
+		want      []patient
+	}{
+		"default threshold": {
+			want: []patient{
+				{Name: "****", ZipCode: "123-4567", Age: 30, Disease: "cold"},
+				{Name: "***", ZipCode: "123-4567", Age: 30, Disease: "flu"},
+				{Name: "**", ZipCode: "123-4567", Age: 41, Disease: "cold"},
+				{Name: "***", ZipCode: "********", Age: 41, Disease: "flu"},
+			},
+		},
+		"threshold 4": {
+			threshold: 4,
+			want: []patient{
+				{Name: "****", ZipCode: "********", Age: 0, Disease: "cold"},
+				{Name: "***", ZipCode: "********", Age: 0, Disease: "flu"},
+				{Name: "**", ZipCode: "********", Age: 0, Disease: "cold"},
+				{Name: "***", ZipCode: "********", Age: 0, Disease: "flu"},
+			},
+		},
+		"threshold 3": {
+			threshold: 3,
+			want: []patient{
+				{Name: "****", ZipCode: "123-4567", Age: 0, Disease: "cold"},
+				{Name: "***", ZipCode: "123-4567", Age: 0, Disease: "flu"},
+				{Name: "**", ZipCode: "123-4567", Age: 0, Disease: "cold"},
+				{Name: "***", ZipCode: "********", Age: 0, Disease: "flu"},
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(defaultTestCase(name), func(t *testing.T) {
+			cleanup(t)
+			if tt.threshold > 0 {
+				SetRareValueThreshold(tt.threshold)
+			}
+			got, err := MaskBatch(input)
+			assert.Nil(t, err)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Error(diff)
+			}
+		})
+		t.Run(newMaskerTestCase(name), func(t *testing.T) {
+			m := newMasker()
+			if tt.threshold > 0 {
+				m.SetRareValueThreshold(tt.threshold)
+			}
+			got, err := MaskBatchWith(m, input)
+			assert.Nil(t, err)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+
+	t.Run("pointers and nil", func(t *testing.T) {
+		m := newMasker()
+		input := []*patient{{ZipCode: "123-4567"}, nil, {ZipCode: "123-4567"}, {ZipCode: "999-0001"}}
+		got, err := MaskBatchWith(m, input)
+		assert.Nil(t, err)
+		want := []*patient{{ZipCode: "123-4567"}, nil, {ZipCode: "123-4567"}, {ZipCode: "********"}}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Error(diff)
+		}
+	})
+	t.Run("rare is kept outside a batch", func(t *testing.T) {
+		m := newMasker()
+		got, err := m.Mask(input[3])
+		assert.Nil(t, err)
+		if diff := cmp.Diff(patient{Name: "***", ZipCode: "999-0001", Age: 41, Disease: "flu"}, got); diff != "" {
+			t.Error(diff)
+		}
+	})
+	t.Run("collect errors", func(t *testing.T) {
+		type errorTest struct {
+			Age int `mask:"randomXX"`
+		}
+		m := newMasker()
+		m.SetErrorMode(ErrorModeCollect)
+		got, err := MaskBatchWith(m, []errorTest{{Age: 1}, {Age: 2}})
+		assert.EqualError(t, err, "[0].Age: strconv.Atoi: parsing \"XX\": invalid syntax\n[1].Age: strconv.Atoi: parsing \"XX\": invalid syntax")
+		assert.Equal(t, []errorTest{{}, {}}, got)
+	})
+}
@@ -0,0 +1,68 @@
+package mask
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Span is the range [Start, End) of bytes in a string found by a Detector.
+type Span struct {
+	Start int
+	End   int
+}
+
+// Detector finds the parts of a string to mask, such as personal information.
+type Detector interface {
+	Detect(s string) []Span
+}
+
+// RegisterDetector registers a detector that can be referred to with a tag like mask:"detect:name"
+// from default masker.
+func RegisterDetector(name string, d Detector) {
+	defaultMasker.RegisterDetector(name, d)
+}
+
+// RegisterDetector registers a detector that can be referred to with a tag like mask:"detect:name".
+// Only the spans found by the detector are masked, and the rest of the string is kept.
+func (m *Masker) RegisterDetector(name string, d Detector) {
+	m.detectorMap[name] = d
+}
+
+// MaskDetectString masks each character in the spans found by the detector registered with the name passed to arg like ":name".
+// The spans out of the string are clipped, and the overlapping spans are masked once.
+func (m *Masker) MaskDetectString(arg, value string) (string, error) {
+	name := strings.TrimPrefix(arg, ":")
+	d, ok := m.detectorMap[name]
+	if !ok {
+		return "", fmt.Errorf("mask: detector %q is not registered", name)
+	}
+
+	spans := d.Detect(value)
+	if len(spans) == 0 {
+		return value, nil
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i].Start < spans[j].Start })
+
+	var sb strings.Builder
+	pos := 0
+	for _, span := range spans {
+		start, end := span.Start, span.End
+		if start < pos {
+			start = pos
+		}
+		if end > len(value) {
+			end = len(value)
+		}
+		if start >= end {
+			continue
+		}
+		sb.WriteString(value[pos:start])
+		sb.WriteString(strings.Repeat(m.maskCharFor(MaskTypeDetect), utf8.RuneCountInString(value[start:end])))
+		pos = end
+	}
+	sb.WriteString(value[pos:])
+
+	return sb.String(), nil
+}
@@ -0,0 +1,101 @@
+package mask
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/assert"
+)
+
+// emailDetector is a stub detector that finds email addresses.
+type emailDetector struct{}
+
+var emailPattern = regexp.MustCompile(`[\w.+-]+@[\w-]+(\.[\w-]+)+`)
+
+func (emailDetector) Detect(s string) []Span {
+	var spans []Span
+	for _, loc := range emailPattern.FindAllStringIndex(s, -1) {
+		spans = append(spans, Span{Start: loc[0], End: loc[1]})
+	}
+	return spans
+}
+
+// spanDetector returns the fixed spans.
+type spanDetector []Span
+
+func (d spanDetector) Detect(string) []Span {
+	return d
+}
+
+func TestMaskDetectString(t *testing.T) {
+	type stringTest struct {
+		Usagi string `mask:"detect:email"`
+	}
+	type stringSliceTest struct {
+		Usagi []string `mask:"detect:email"`
+	}
+	type spanTest struct {
+		Usagi string `mask:"detect:span"`
+	}
+
+	tests := map[string]struct {
+		input any
+		want  any
+	}{
+		"email in text": {
+			input: &stringTest{Usagi: "contact usagi@example.com for details"},
+			want:  &stringTest{Usagi: "contact ***************** for details"},
+		},
+		"emails in text": {
+			input: &stringTest{Usagi: "ヤハッ！ a@b.jp, hachiware@example.co.jp"},
+			want:  &stringTest{Usagi: "ヤハッ！ ******, ***********************"},
+		},
+		"no email": {
+			input: &stringTest{Usagi: "ハァ？"},
+			want:  &stringTest{Usagi: "ハァ？"},
+		},
+		"slice": {
+			input: &stringSliceTest{Usagi: []string{"ウラ <u@x.io>", "フゥン"}},
+			want:  &stringSliceTest{Usagi: []string{"ウラ <******>", "フゥン"}},
+		},
+		"overlapping, unsorted and out of range spans": {
+			input: &spanTest{Usagi: "ヤハッ！ウラ"},
+			want:  &spanTest{Usagi: "*****ラ"},
+		},
+		"zero string fields": {
+			input: &stringTest{},
+			want:  &stringTest{Usagi: ""},
+		},
+	}
+	// "ヤハッ！ウラ" has 3 bytes per character
+	spans := spanDetector{{Start: 6, End: 15}, {Start: -1, End: 3}, {Start: 3, End: 9}, {Start: 18, End: 30}}
+
+	for name, tt := range tests {
+		t.Run(defaultTestCase(name), func(t *testing.T) {
+			cleanup(t)
+			RegisterDetector("email", emailDetector{})
+			RegisterDetector("span", spans)
+			got, err := Mask(tt.input)
+			assert.Nil(t, err)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Error(diff)
+			}
+		})
+		t.Run(newMaskerTestCase(name), func(t *testing.T) {
+			m := newMasker()
+			m.RegisterDetector("email", emailDetector{})
+			m.RegisterDetector("span", spans)
+			got, err := m.Mask(tt.input)
+			assert.Nil(t, err)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+
+	t.Run("not registered", func(t *testing.T) {
+		_, err := newMasker().MaskDetectString(":email", "usagi@example.com")
+		assert.EqualError(t, err, `mask: detector "email" is not registered`)
+	})
+}
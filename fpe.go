@@ -0,0 +1,186 @@
+package mask
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// fpeRounds is the number of Feistel rounds of the format-preserving encryption.
+const fpeRounds = 10
+
+// SetFPEKey sets the key used by the "fpe" mask.
+// from default masker.
+func SetFPEKey(key []byte) {
+	defaultMasker.SetFPEKey(key)
+}
+
+// DecryptFPE restores a string masked by the "fpe" mask.
+// from default masker.
+func DecryptFPE(masked string) (string, error) {
+	return defaultMasker.DecryptFPE(masked)
+}
+
+// DecryptFPEInt restores an integer masked by the "fpe" mask.
+// from default masker.
+func DecryptFPEInt(masked int) (int, error) {
+	return defaultMasker.DecryptFPEInt(masked)
+}
+
+// SetFPEKey sets the key used by the "fpe" mask.
+// Any length of key can be used, but it should be at least 32 bytes of random data.
+func (m *Masker) SetFPEKey(key []byte) {
+	m.fpeKey = append([]byte(nil), key...)
+}
+
+// MaskFPEString encrypts the digits in a string with format-preserving encryption.
+// An N-digit number is deterministically converted to another N-digit number, and the characters other than digits are kept in place.
+// The value can be restored with DecryptFPE.
+func (m *Masker) MaskFPEString(arg, value string) (string, error) {
+	return m.fpeString(value, true)
+}
+
+// DecryptFPE restores a string masked by MaskFPEString.
+func (m *Masker) DecryptFPE(masked string) (string, error) {
+	return m.fpeString(masked, false)
+}
+
+// MaskFPEInt encrypts an integer with format-preserving encryption.
+// The sign and the number of digits are kept, and the value can be restored with DecryptFPEInt.
+// When masking a smaller int type, such as int8, an error is returned if the result does not fit in the type,
+// since a saturated value could not be decrypted.
+func (m *Masker) MaskFPEInt(arg string, value int) (int, error) {
+	return m.fpeInt(value, true)
+}
+
+// DecryptFPEInt restores an integer masked by MaskFPEInt.
+func (m *Masker) DecryptFPEInt(masked int) (int, error) {
+	return m.fpeInt(masked, false)
+}
+
+func (m *Masker) fpeString(value string, encrypt bool) (string, error) {
+	if len(m.fpeKey) == 0 {
+		return "", errors.New("mask: FPE key is not set")
+	}
+
+	digits := make([]byte, 0, len(value))
+	for i := 0; i < len(value); i++ {
+		if isDigit(rune(value[i])) {
+			digits = append(digits, value[i])
+		}
+	}
+	if len(digits) == 0 {
+		return value, nil
+	}
+	digits = m.fpeFeistel(digits, encrypt)
+
+	b := []byte(value)
+	j := 0
+	for i := range b {
+		if isDigit(rune(b[i])) {
+			b[i] = digits[j]
+			j++
+		}
+	}
+
+	return string(b), nil
+}
+
+func (m *Masker) fpeInt(value int, encrypt bool) (int, error) {
+	if len(m.fpeKey) == 0 {
+		return 0, errors.New("mask: FPE key is not set")
+	}
+
+	s := strconv.Itoa(value)
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	if _, ok := fpeIntDigits(s); !ok {
+		return 0, errors.New("mask: FPE cannot encrypt " + sign + s)
+	}
+
+	// Cycle-walk until the digits form a number without a leading zero that fits in int,
+	// so that the number of digits is kept and the result can be decrypted in the same way.
+	digits := []byte(s)
+	for {
+		digits = m.fpeFeistel(digits, encrypt)
+		if n, ok := fpeIntDigits(string(digits)); ok {
+			if sign != "" {
+				n = -n
+			}
+			return n, nil
+		}
+	}
+}
+
+// fpeIntDigits parses the digits as a number in the domain of MaskFPEInt.
+func fpeIntDigits(s string) (int, bool) {
+	if len(s) > 1 && s[0] == '0' {
+		return 0, false
+	}
+	n, err := strconv.Atoi(s)
+	return n, err == nil
+}
+
+// fpeFeistel encrypts or decrypts decimal digits with an FF1-like Feistel network,
+// using HMAC-SHA256 with the FPE key as the round function.
+func (m *Masker) fpeFeistel(digits []byte, encrypt bool) []byte {
+	n := len(digits)
+	u := n / 2
+	a, b := string(digits[:u]), string(digits[u:])
+
+	if encrypt {
+		for i := 0; i < fpeRounds; i++ {
+			size := fpeRoundSize(i, n)
+			c := new(big.Int).Add(fpeNum(a), m.fpeRound(i, n, b))
+			a, b = b, fpeStr(c.Mod(c, fpeModulus(size)), size)
+		}
+	} else {
+		for i := fpeRounds - 1; i >= 0; i-- {
+			size := fpeRoundSize(i, n)
+			c := new(big.Int).Sub(fpeNum(b), m.fpeRound(i, n, a))
+			a, b = fpeStr(c.Mod(c, fpeModulus(size)), size), a
+		}
+	}
+
+	return []byte(a + b)
+}
+
+// fpeRoundSize returns the number of digits of the half updated in round i.
+func fpeRoundSize(i, n int) int {
+	if i%2 == 0 {
+		return n / 2
+	}
+	return n - n/2
+}
+
+func (m *Masker) fpeRound(i, n int, half string) *big.Int {
+	mac := hmac.New(sha256.New, m.fpeKey)
+	mac.Write([]byte{byte(i)})
+	mac.Write([]byte(strconv.Itoa(n) + ":" + half))
+	return new(big.Int).SetBytes(mac.Sum(nil))
+}
+
+func fpeModulus(size int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(size)), nil)
+}
+
+func fpeNum(s string) *big.Int {
+	n, _ := new(big.Int).SetString(s, 10)
+	if n == nil {
+		return new(big.Int)
+	}
+	return n
+}
+
+func fpeStr(n *big.Int, size int) string {
+	if size == 0 {
+		return ""
+	}
+	s := n.String()
+	return strings.Repeat("0", size-len(s)) + s
+}
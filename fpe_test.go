@@ -0,0 +1,208 @@
+package mask
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testFPEKey = []byte("0123456789abcdef0123456789abcdef")
+
+func TestMaskFPEString(t *testing.T) {
+	type stringTest struct {
+		Usagi string `mask:"fpe"`
+	}
+
+	tests := map[string]struct {
+		input string
+	}{
+		"1 digit":            {input: "7"},
+		"2 digits":           {input: "42"},
+		"5 digits":           {input: "01234"},
+		"10 digits":          {input: "9876543210"},
+		"16 digits":          {input: "1234567890123456"},
+		"40 digits":          {input: strings.Repeat("1234", 10)},
+		"digits with dashes": {input: "1234-5678-9012"},
+		"no digits":          {input: "ヤハッ！"},
+	}
+
+	for name, tt := range tests {
+		t.Run(defaultTestCase(name), func(t *testing.T) {
+			cleanup(t)
+			SetFPEKey(testFPEKey)
+			got, err := Mask(&stringTest{Usagi: tt.input})
+			assert.Nil(t, err)
+			assertFPEString(t, tt.input, got.Usagi)
+			decrypted, err := DecryptFPE(got.Usagi)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.input, decrypted)
+		})
+		t.Run(newMaskerTestCase(name), func(t *testing.T) {
+			m := newMasker()
+			m.SetFPEKey(testFPEKey)
+			got, err := MaskTypedWith(m, &stringTest{Usagi: tt.input})
+			assert.Nil(t, err)
+			assertFPEString(t, tt.input, got.Usagi)
+			decrypted, err := m.DecryptFPE(got.Usagi)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.input, decrypted)
+
+			// deterministic
+			again, err := MaskTypedWith(m, &stringTest{Usagi: tt.input})
+			assert.Nil(t, err)
+			assert.Equal(t, got, again)
+		})
+	}
+
+	t.Run("different keys", func(t *testing.T) {
+		m1, m2 := newMasker(), newMasker()
+		m1.SetFPEKey(testFPEKey)
+		m2.SetFPEKey([]byte("another key"))
+		got1, err := m1.String(MaskTypeFPE, "1234567890")
+		assert.Nil(t, err)
+		got2, err := m2.String(MaskTypeFPE, "1234567890")
+		assert.Nil(t, err)
+		assert.NotEqual(t, got1, got2)
+	})
+	t.Run("key is not set", func(t *testing.T) {
+		m := newMasker()
+		_, err := m.Mask(&stringTest{Usagi: "1234"})
+		assert.EqualError(t, err, "mask: FPE key is not set")
+	})
+}
+
+func assertFPEString(t *testing.T, input, got string) {
+	t.Helper()
+	if assert.Equal(t, len(input), len(got)) {
+		for i := 0; i < len(input); i++ {
+			if isDigit(rune(input[i])) {
+				assert.True(t, isDigit(rune(got[i])), got)
+			} else {
+				assert.Equal(t, input[i], got[i], got)
+			}
+		}
+	}
+	if strings.IndexFunc(input, isDigit) >= 0 && len(input) >= 10 {
+		assert.NotEqual(t, input, got)
+	}
+}
+
+func TestMaskFPEInt(t *testing.T) {
+	type intTest struct {
+		Usagi int `mask:"fpe"`
+	}
+	type int64Test struct {
+		Usagi int64 `mask:"fpe"`
+	}
+	type int8Test struct {
+		Usagi int8 `mask:"fpe"`
+	}
+	type int16KeysTest struct {
+		Usagi map[int16]string `mask:",keys:fpe"`
+	}
+
+	tests := map[string]struct {
+		input int
+	}{
+		"zero":     {input: 0},
+		"1 digit":  {input: 7},
+		"2 digits": {input: 10},
+		"6 digits": {input: 123456},
+		"negative": {input: -98765},
+		"max int":  {input: math.MaxInt},
+		"min int":  {input: math.MinInt + 1},
+	}
+
+	for name, tt := range tests {
+		t.Run(defaultTestCase(name), func(t *testing.T) {
+			cleanup(t)
+			SetFPEKey(testFPEKey)
+			got, err := Mask(&intTest{Usagi: tt.input})
+			assert.Nil(t, err)
+			assertFPEInt(t, tt.input, got.Usagi)
+			decrypted, err := DecryptFPEInt(got.Usagi)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.input, decrypted)
+		})
+		t.Run(newMaskerTestCase(name), func(t *testing.T) {
+			m := newMasker()
+			m.SetFPEKey(testFPEKey)
+			got, err := MaskTypedWith(m, &intTest{Usagi: tt.input})
+			assert.Nil(t, err)
+			assertFPEInt(t, tt.input, got.Usagi)
+			decrypted, err := m.DecryptFPEInt(got.Usagi)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.input, decrypted)
+		})
+	}
+
+	t.Run("int64 fields", func(t *testing.T) {
+		m := newMasker()
+		m.SetFPEKey(testFPEKey)
+		got, err := MaskTypedWith(m, &int64Test{Usagi: 20190122})
+		assert.Nil(t, err)
+		assertFPEInt(t, 20190122, int(got.Usagi))
+	})
+	t.Run("all 4 digit numbers", func(t *testing.T) {
+		m := newMasker()
+		m.SetFPEKey(testFPEKey)
+		seen := make(map[int]bool)
+		for i := 1000; i < 10000; i++ {
+			got, err := m.MaskFPEInt("", i)
+			assert.Nil(t, err)
+			assert.False(t, seen[got], got)
+			seen[got] = true
+		}
+		assert.Len(t, seen, 9000)
+	})
+	t.Run("min int", func(t *testing.T) {
+		m := newMasker()
+		m.SetFPEKey(testFPEKey)
+		_, err := m.MaskFPEInt("", math.MinInt)
+		assert.Error(t, err)
+	})
+	t.Run("all int8 numbers", func(t *testing.T) {
+		m := newMasker()
+		m.SetFPEKey(testFPEKey)
+		overflows := 0
+		for i := math.MinInt8; i <= math.MaxInt8; i++ {
+			got, err := MaskTypedWith(m, &int8Test{Usagi: int8(i)})
+			if err != nil {
+				// not saturated, since a saturated value could not be decrypted
+				assert.Regexp(t, `^mask: FPE result -?\d+ overflows int8$`, err.Error())
+				overflows++
+				continue
+			}
+			decrypted, err := m.DecryptFPEInt(int(got.Usagi))
+			assert.Nil(t, err)
+			assert.Equal(t, i, decrypted)
+		}
+		assert.NotZero(t, overflows)
+	})
+	t.Run("int16 keys", func(t *testing.T) {
+		m := newMasker()
+		m.SetFPEKey(testFPEKey)
+		for i := 10000; i <= math.MaxInt16; i++ {
+			got, err := m.Mask(&int16KeysTest{Usagi: map[int16]string{int16(i): "ウラ"}})
+			if err != nil {
+				assert.Regexp(t, `^mask: FPE result \d+ overflows int16$`, err.Error())
+				return
+			}
+			for k := range got.(*int16KeysTest).Usagi {
+				decrypted, err := m.DecryptFPEInt(int(k))
+				assert.Nil(t, err)
+				assert.Equal(t, i, decrypted)
+			}
+		}
+		t.Error("no overflow")
+	})
+}
+
+func assertFPEInt(t *testing.T, input, got int) {
+	t.Helper()
+	assert.Equal(t, len(strconv.Itoa(input)), len(strconv.Itoa(got)), got)
+	assert.Equal(t, input < 0, got < 0, got)
+}
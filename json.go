@@ -0,0 +1,402 @@
+package mask
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+
+// MarshalOption is an option for MarshalMasked.
+type MarshalOption func(*marshalOptions)
+
+type marshalOptions struct {
+	maskMarshalers bool
+}
+
+// WithMaskMarshalers masks the values implementing json.Marshaler by their marshalled form.
+// Since the fields of such types may not be reflected in the output of MarshalJSON,
+// the untagged values of such types are marshalled as they are, and the registered field rules are applied to the keys of their JSON
+// in the same way as MaskJSON. The rest of the output is masked as usual, and a value with a tag or a registered handler,
+// such as a time.Time tagged with mask:"zero", is masked by it.
+// Each such value is replaced only at its own position in the output, so another value with the same encoding is masked only by its own tag.
+// This costs an extra marshal of such values and a scan of the whole output, so use it only when needed.
+func WithMaskMarshalers() MarshalOption {
+	return func(o *marshalOptions) {
+		o.maskMarshalers = true
+	}
+}
+
+// MarshalMasked returns the JSON encoding of the given object with the mask applied.
+// from default masker.
+func MarshalMasked(target any, opts ...MarshalOption) ([]byte, error) {
+	return defaultMasker.MarshalMasked(target, opts...)
+}
+
+// MarshalMasked returns the JSON encoding of the given object with the mask applied.
+func (m *Masker) MarshalMasked(target any, opts ...MarshalOption) ([]byte, error) {
+	var o marshalOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	s := m.newMaskState()
+	if o.maskMarshalers {
+		s.keepMarshalers = true
+		// the Marshalers are replaced by their paths, so a value shared by several paths is masked for each of them
+		s.trackPath = true
+		if s.ancestors == nil {
+			s.ancestors = make(map[visitKey]reflect.Value)
+		}
+	}
+	rv, err := m.mask(s, reflect.ValueOf(target), "", reflect.Value{})
+	if err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(rv.Interface())
+	if err != nil {
+		return nil, err
+	}
+	if len(s.marshaled) > 0 {
+		if b, err = replaceJSON(b, "", s.marshaled); err != nil {
+			return nil, err
+		}
+	}
+
+	// the JSON is returned with the errors in ErrorModeCollect
+	return b, s.collectedError()
+}
+
+// MaskWithSizeReport returns an object with the mask applied and the approximate number of bytes removed by the mask
+// from default masker.
+func MaskWithSizeReport(target any) (any, int, error) {
+	return defaultMasker.MaskWithSizeReport(target)
+}
+
+// MaskWithSizeReport returns an object with the mask applied and the approximate number of bytes removed by the mask,
+// which is the difference between the sizes of the JSON encodings of the object before and after masking.
+// It is useful to know how much a mask such as "width" or "zero" reduces the size of logs.
+// The number is negative if the mask makes the object larger, as "fixed" does for a short string.
+func (m *Masker) MaskWithSizeReport(target any) (any, int, error) {
+	before, err := json.Marshal(target)
+	if err != nil {
+		return nil, 0, err
+	}
+	masked, maskErr := m.Mask(target)
+	if masked == nil && maskErr != nil {
+		return nil, 0, maskErr
+	}
+	after, err := json.Marshal(masked)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// the masked value is returned with the errors in ErrorModeCollect
+	return masked, len(before) - len(after), maskErr
+}
+
+// MaskJSON applies the registered field rules to the keys of the JSON objects in data, and returns the JSON encoding of the result
+// from default masker.
+func MaskJSON(data []byte) ([]byte, error) {
+	return defaultMasker.MaskJSON(data)
+}
+
+// MaskJSON applies the registered field rules, such as the ones registered with RegisterMaskField, to the keys of the JSON objects in data,
+// including the objects nested in other objects and arrays, and returns the JSON encoding of the result.
+// Integers are decoded as int64 and other numbers as float64, so that an integer larger than float64 can represent exactly is kept as it is.
+// The keys of the objects are sorted in the output, as encoding/json does for maps.
+func (m *Masker) MaskJSON(data []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("mask: invalid data after the JSON value")
+	}
+
+	return m.maskDecodedJSON(v)
+}
+
+// maskDecodedJSON applies the registered field rules to a value decoded with json.Decoder.UseNumber, and encodes it again.
+func (m *Masker) maskDecodedJSON(v any) ([]byte, error) {
+	masked, err := m.Mask(decodeJSONNumbers(v))
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(masked)
+}
+
+// NewJSONMaskWriter returns a writer that masks the JSON values written to it and writes them to w.
+// The registered field rules, such as the ones registered with RegisterMaskField, are applied to the keys of the JSON objects,
+// so that it can wrap the output of a logger writing JSON.
+// A JSON value can be written in parts, and a write can contain several JSON values. A value is written to w when it is complete,
+// and the whitespace between values, such as newlines, is kept. If m is nil, the default masker is used.
+// If the data is not valid JSON, an error is returned and the data buffered so far is discarded, so that it is not written unmasked.
+func NewJSONMaskWriter(w io.Writer, m *Masker) io.Writer {
+	if m == nil {
+		m = defaultMasker
+	}
+	return &jsonMaskWriter{w: w, m: m}
+}
+
+type jsonMaskWriter struct {
+	mu  sync.Mutex
+	w   io.Writer
+	m   *Masker
+	buf []byte
+}
+
+func (w *jsonMaskWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf = append(w.buf, p...)
+	for {
+		// the whitespace before a value is written as it is
+		if n := len(w.buf) - len(bytes.TrimLeft(w.buf, " \t\r\n")); n > 0 {
+			if _, err := w.w.Write(w.buf[:n]); err != nil {
+				return 0, err
+			}
+			w.buf = w.buf[n:]
+		}
+		if len(w.buf) == 0 {
+			return len(p), nil
+		}
+
+		dec := json.NewDecoder(bytes.NewReader(w.buf))
+		dec.UseNumber()
+		var v any
+		if err := dec.Decode(&v); err != nil {
+			if errors.Is(err, io.ErrUnexpectedEOF) {
+				// wait for the rest of the value
+				return len(p), nil
+			}
+			w.buf = nil
+			return 0, err
+		}
+		b, err := w.m.maskDecodedJSON(v)
+		if err != nil {
+			w.buf = w.buf[dec.InputOffset():]
+			return 0, err
+		}
+		if _, err := w.w.Write(b); err != nil {
+			return 0, err
+		}
+		w.buf = w.buf[dec.InputOffset():]
+	}
+}
+
+// decodeJSONNumbers converts the json.Number values in decoded JSON to int64 or float64,
+// so that they are masked as numbers instead of strings.
+func decodeJSONNumbers(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			v[key] = decodeJSONNumbers(value)
+		}
+	case []any:
+		for i, value := range v {
+			v[i] = decodeJSONNumbers(value)
+		}
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		if f, err := v.Float64(); err == nil {
+			return f
+		}
+	}
+
+	return v
+}
+
+// marshaledJSON is the JSON encoding of a value implementing json.Marshaler and its masked form.
+type marshaledJSON struct {
+	original []byte
+	masked   []byte
+}
+
+// keepMarshaler keeps a value implementing json.Marshaler as it is, and records the masked form of its JSON encoding
+// by its path, which replaces it in the output of MarshalMasked.
+func (m *Masker) keepMarshaler(s *maskState, rv reflect.Value) (reflect.Value, error) {
+	path, ok := s.jsonPath()
+	if !ok || !rv.CanInterface() {
+		return rv, nil
+	}
+	if err := m.recordMarshaled(s, path, rv.Interface()); err != nil {
+		return reflect.Value{}, err
+	}
+	if !rv.Type().Implements(jsonMarshalerType) {
+		// MarshalJSON has a pointer receiver, so it is called only if the value is addressable in the output
+		p := reflect.New(rv.Type())
+		p.Elem().Set(rv)
+		if err := m.recordMarshaled(s, path, p.Interface()); err != nil {
+			return reflect.Value{}, err
+		}
+	}
+
+	return rv, nil
+}
+
+func (m *Masker) recordMarshaled(s *maskState, path string, v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	masked, err := m.MaskJSON(b)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(b, masked) {
+		if s.marshaled == nil {
+			s.marshaled = make(map[string][]marshaledJSON)
+		}
+		s.marshaled[path] = append(s.marshaled[path], marshaledJSON{original: b, masked: masked})
+	}
+
+	return nil
+}
+
+// jsonPath returns the path to the current value in the JSON encoding, with the object keys and array indexes
+// each preceded by a zero byte, and whether the value is in the encoding at all.
+func (s *maskState) jsonPath() (string, bool) {
+	var sb strings.Builder
+	for _, seg := range s.path {
+		var name string
+		switch {
+		case seg.isIndex:
+			name = strconv.Itoa(seg.index)
+		case seg.key.IsValid():
+			var ok bool
+			if name, ok = jsonKeyName(seg.key); !ok {
+				return "", false
+			}
+		default:
+			var inline, ok bool
+			if name, inline, ok = jsonFieldKey(seg.parent.Field(seg.field)); !ok {
+				return "", false
+			}
+			if inline {
+				// the fields of an embedded struct are in the object of the outer struct
+				continue
+			}
+		}
+		sb.WriteByte(0)
+		sb.WriteString(name)
+	}
+
+	return sb.String(), true
+}
+
+// jsonFieldKey returns the key of a struct field in the JSON encoding as encoding/json does,
+// whether its fields are inlined in the outer object as an embedded struct, and whether the field is encoded.
+func jsonFieldKey(field reflect.StructField) (string, bool, bool) {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false, false
+	}
+	if name, _, _ := strings.Cut(tag, ","); field.Anonymous && name == "" {
+		t := field.Type
+		if t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		if t.Kind() == reflect.Struct {
+			return "", true, true
+		}
+	}
+	if field.PkgPath != "" {
+		return "", false, false
+	}
+
+	return jsonFieldName(field), false, true
+}
+
+// jsonKeyName returns the key of a map entry in the JSON encoding as encoding/json does, and whether it can be encoded.
+func jsonKeyName(key reflect.Value) (string, bool) {
+	if key.Kind() == reflect.String {
+		return key.String(), true
+	}
+	if key.CanInterface() {
+		if tm, ok := key.Interface().(encoding.TextMarshaler); ok {
+			if key.Kind() == reflect.Pointer && key.IsNil() {
+				return "", false
+			}
+			b, err := tm.MarshalText()
+			return string(b), err == nil
+		}
+	}
+	switch key.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(key.Int(), 10), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(key.Uint(), 10), true
+	}
+
+	return "", false
+}
+
+// replaceJSON replaces the values in the JSON data at the paths recorded in replaced, see jsonPath,
+// if they are still the recorded encodings, keeping the rest of the data as it is.
+func replaceJSON(data []byte, path string, replaced map[string][]marshaledJSON) ([]byte, error) {
+	for _, r := range replaced[path] {
+		if bytes.Equal(data, r.original) {
+			return r.masked, nil
+		}
+	}
+	if len(data) == 0 || (data[0] != '{' && data[0] != '[') {
+		return data, nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	buf.WriteByte(data[0])
+	for i := 0; dec.More(); i++ {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name := strconv.Itoa(i)
+		if data[0] == '{' {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			b, err := json.Marshal(key)
+			if err != nil {
+				return nil, err
+			}
+			buf.Write(b)
+			buf.WriteByte(':')
+			name = key.(string)
+		}
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil, err
+		}
+		v, err := replaceJSON(raw, path+"\x00"+name, replaced)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(v)
+	}
+	buf.WriteByte(data[len(data)-1])
+
+	return buf.Bytes(), nil
+}
+
+// isJSONMarshaler reports whether the value or a pointer to it implements json.Marshaler.
+func isJSONMarshaler(rt reflect.Type) bool {
+	return rt.Implements(jsonMarshalerType) || reflect.PtrTo(rt).Implements(jsonMarshalerType)
+}
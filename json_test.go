@@ -0,0 +1,352 @@
+package mask
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/assert"
+)
+
+type jsonMarshalerTest struct {
+	name  string
+	email string
+}
+
+func (v jsonMarshalerTest) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]any{"Name": v.name, "Email": v.email, "Rank": len(v.name)})
+}
+
+func TestMarshalMasked(t *testing.T) {
+	type userTest struct {
+		ID      string `mask:"filled"`
+		Profile jsonMarshalerTest
+		Ptr     *jsonMarshalerTest
+		Age     int
+	}
+
+	input := userTest{
+		ID:      "ヤハッ！",
+		Profile: jsonMarshalerTest{name: "Usagi", email: "usagi@example.com"},
+		Ptr:     &jsonMarshalerTest{name: "Momo", email: "momo@example.com"},
+		Age:     10,
+	}
+	tests := map[string]struct {
+		opts []MarshalOption
+		want string
+	}{
+		"without option": {
+			want: `{"ID":"****","Profile":{"Email":"","Name":"","Rank":0},"Ptr":{"Email":"","Name":"","Rank":0},"Age":10}`,
+		},
+		"with mask marshalers": {
+			opts: []MarshalOption{WithMaskMarshalers()},
+			want: `{"ID":"****","Profile":{"Email":"****","Name":"Usagi","Rank":0},"Ptr":{"Email":"****","Name":"Momo","Rank":0},"Age":10}`,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(defaultTestCase(name), func(t *testing.T) {
+			cleanup(t)
+			RegisterMaskField("Email", "filled4")
+			RegisterMaskField("Rank", "zero")
+			got, err := MarshalMasked(input, tt.opts...)
+			assert.Nil(t, err)
+			if diff := cmp.Diff(tt.want, string(got)); diff != "" {
+				t.Error(diff)
+			}
+		})
+		t.Run(newMaskerTestCase(name), func(t *testing.T) {
+			m := newMasker()
+			m.RegisterMaskField("Email", "filled4")
+			m.RegisterMaskField("Rank", "zero")
+			got, err := m.MarshalMasked(input, tt.opts...)
+			assert.Nil(t, err)
+			if diff := cmp.Diff(tt.want, string(got)); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestMarshalMasked_TaggedMarshalers(t *testing.T) {
+	type userTest struct {
+		Birthday time.Time `mask:"zero"`
+		Password string    `mask:"hash"`
+		Created  time.Time
+		Profile  jsonMarshalerTest
+	}
+
+	input := &userTest{
+		Birthday: time.Date(2000, 1, 2, 0, 0, 0, 0, time.UTC),
+		Password: "ヤハッ！",
+		Created:  time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC),
+		Profile:  jsonMarshalerTest{name: "Usagi", email: "usagi@example.com"},
+	}
+	want := `{"Birthday":"0001-01-01T00:00:00Z","Password":"a6ab5728db57954641b2e155adc61f2cbdfc7063","Created":"2020-01-02T00:00:00Z","Profile":{"Email":"****","Name":"Usagi","Rank":5}}`
+
+	t.Run(defaultTestCase("tagged marshalers"), func(t *testing.T) {
+		cleanup(t)
+		RegisterMaskField("Email", "filled4")
+		got, err := MarshalMasked(input, WithMaskMarshalers())
+		assert.Nil(t, err)
+		if diff := cmp.Diff(want, string(got)); diff != "" {
+			t.Error(diff)
+		}
+	})
+	t.Run(newMaskerTestCase("tagged marshalers"), func(t *testing.T) {
+		m := newMasker()
+		m.RegisterMaskField("Email", "filled4")
+		got, err := m.MarshalMasked(input, WithMaskMarshalers())
+		assert.Nil(t, err)
+		if diff := cmp.Diff(want, string(got)); diff != "" {
+			t.Error(diff)
+		}
+		// the hashed field is hashed once, as without the option
+		b, err := m.MarshalMasked(input)
+		assert.Nil(t, err)
+		var masked userTest
+		assert.Nil(t, json.Unmarshal(b, &masked))
+		assert.Equal(t, "a6ab5728db57954641b2e155adc61f2cbdfc7063", masked.Password)
+	})
+}
+
+func TestMarshalMasked_SameEncoding(t *testing.T) {
+	type plainProfileTest struct {
+		Email string `mask:"keep"`
+		Name  string
+		Rank  int
+	}
+	type EmbeddedTest struct {
+		Inner jsonMarshalerTest `json:"inner"`
+	}
+	type userTest struct {
+		EmbeddedTest
+		Profile  jsonMarshalerTest `json:"profile"`
+		Plain    plainProfileTest
+		Profiles []jsonMarshalerTest
+		Plains   map[string]plainProfileTest
+	}
+
+	profile := jsonMarshalerTest{name: "Usagi", email: "usagi@example.com"}
+	plain := plainProfileTest{Email: "usagi@example.com", Name: "Usagi", Rank: 5}
+	input := &userTest{
+		EmbeddedTest: EmbeddedTest{Inner: profile},
+		Profile:      profile,
+		Plain:        plain,
+		Profiles:     []jsonMarshalerTest{profile},
+		Plains:       map[string]plainProfileTest{"profile": plain},
+	}
+	masked := `{"Email":"****","Name":"Usagi","Rank":5}`
+	unmasked := `{"Email":"usagi@example.com","Name":"Usagi","Rank":5}`
+	want := `{"inner":` + masked + `,"profile":` + masked + `,"Plain":` + unmasked +
+		`,"Profiles":[` + masked + `],"Plains":{"profile":` + unmasked + `}}`
+
+	t.Run(defaultTestCase("same encoding"), func(t *testing.T) {
+		cleanup(t)
+		RegisterMaskField("Email", "filled4")
+		got, err := MarshalMasked(input, WithMaskMarshalers())
+		assert.Nil(t, err)
+		if diff := cmp.Diff(want, string(got)); diff != "" {
+			t.Error(diff)
+		}
+	})
+	t.Run(newMaskerTestCase("same encoding"), func(t *testing.T) {
+		m := newMasker()
+		m.RegisterMaskField("Email", "filled4")
+		got, err := m.MarshalMasked(input, WithMaskMarshalers())
+		assert.Nil(t, err)
+		if diff := cmp.Diff(want, string(got)); diff != "" {
+			t.Error(diff)
+		}
+	})
+}
+
+func TestMaskJSON(t *testing.T) {
+	tests := map[string]struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		"nested objects": {
+			input: `{"name":"ヤハッ！","profile":{"name":"ウラ","age":3,"email":"usagi@example.com"}}`,
+			want:  `{"name":"****","profile":{"age":0,"email":"********","name":"**"}}`,
+		},
+		"arrays of objects": {
+			input: `{"users":[{"name":"ハァ？","tags":["a"]},{"name":"フゥン"}],"count":2}`,
+			want:  `{"count":2,"users":[{"name":"***","tags":["a"]},{"name":"***"}]}`,
+		},
+		"top-level array": {
+			input: `[{"name":"ウラ"},"ヤハッ！",null]`,
+			want:  `[{"name":"**"},"ヤハッ！",null]`,
+		},
+		"numbers": {
+			input: `{"id":9007199254740993,"score":1.25,"ratio":1e-3}`,
+			want:  `{"id":9007199254740993,"ratio":0.001,"score":1.25}`,
+		},
+		"masked numbers": {
+			input: `{"age":30,"rate":0.5}`,
+			want:  `{"age":0,"rate":0}`,
+		},
+		"invalid json": {
+			input:   `{"name":`,
+			wantErr: true,
+		},
+		"trailing data": {
+			input:   `{"name":"ウラ"} {}`,
+			wantErr: true,
+		},
+	}
+
+	register := func(m *Masker) {
+		m.RegisterMaskField("name", "filled")
+		m.RegisterMaskField("email", "fixed")
+		m.RegisterMaskField("age", "zero")
+		m.RegisterMaskField("rate", "zero")
+	}
+	for name, tt := range tests {
+		t.Run(defaultTestCase(name), func(t *testing.T) {
+			cleanup(t)
+			register(defaultMasker)
+			got, err := MaskJSON([]byte(tt.input))
+			if tt.wantErr {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, string(got))
+		})
+		t.Run(newMaskerTestCase(name), func(t *testing.T) {
+			m := newMasker()
+			register(m)
+			got, err := m.MaskJSON([]byte(tt.input))
+			if tt.wantErr {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, string(got))
+		})
+	}
+}
+
+func TestMaskWithSizeReport(t *testing.T) {
+	type logTest struct {
+		Comment string `json:"comment" mask:"width3"`
+		Token   string `json:"token" mask:"zero"`
+		Name    string `json:"name"`
+	}
+	type fixedTest struct {
+		Name string `mask:"fixed"`
+	}
+
+	tests := map[string]struct {
+		input     any
+		want      any
+		wantSaved int
+	}{
+		"truncated": {
+			input:     &logTest{Comment: "usagi-chan", Token: "abcdef", Name: "ウラ"},
+			want:      &logTest{Comment: "usa", Name: "ウラ"},
+			wantSaved: 13,
+		},
+		"nothing masked": {
+			input:     &logTest{Name: "ウラ"},
+			want:      &logTest{Name: "ウラ"},
+			wantSaved: 0,
+		},
+		"larger": {
+			input:     fixedTest{Name: "ab"},
+			want:      fixedTest{Name: "********"},
+			wantSaved: -6,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(defaultTestCase(name), func(t *testing.T) {
+			cleanup(t)
+			got, saved, err := MaskWithSizeReport(tt.input)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantSaved, saved)
+		})
+		t.Run(newMaskerTestCase(name), func(t *testing.T) {
+			got, saved, err := newMasker().MaskWithSizeReport(tt.input)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantSaved, saved)
+		})
+	}
+}
+
+func TestNewJSONMaskWriter(t *testing.T) {
+	tests := map[string]struct {
+		writes  []string
+		want    string
+		wantErr bool
+	}{
+		"object": {
+			writes: []string{`{"name":"ヤハッ！","id":1}` + "\n"},
+			want:   `{"id":1,"name":"****"}` + "\n",
+		},
+		"partial writes": {
+			writes: []string{`{"name":"ヤハ`, `ッ！","nested":{"na`, `me":"ウラ"},"users":[{"name":"ハァ？"}]}`, "\n"},
+			want:   `{"name":"****","nested":{"name":"**"},"users":[{"name":"***"}]}` + "\n",
+		},
+		"concatenated values": {
+			writes: []string{`{"name":"ヤハッ！"}` + "\n" + `{"name":"ウラ","score":1.5}` + "\n" + `{"na`, `me":"フゥン"}` + "\n"},
+			want:   `{"name":"****"}` + "\n" + `{"name":"**","score":1.5}` + "\n" + `{"name":"***"}` + "\n",
+		},
+		"array": {
+			writes: []string{` [{"name":"ウラ"}, "ヤハッ！"] `},
+			want:   ` [{"name":"**"},"ヤハッ！"] `,
+		},
+		"invalid json": {
+			writes:  []string{`{"name":"ウラ"}` + "\n" + `name=ウラ`},
+			want:    `{"name":"**"}` + "\n",
+			wantErr: true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(defaultTestCase(name), func(t *testing.T) {
+			cleanup(t)
+			RegisterMaskField("name", "filled")
+			var buf bytes.Buffer
+			w := NewJSONMaskWriter(&buf, nil)
+			var err error
+			for _, s := range tt.writes {
+				var n int
+				if n, err = w.Write([]byte(s)); err != nil {
+					break
+				}
+				assert.Equal(t, len(s), n)
+			}
+			if tt.wantErr {
+				assert.NotNil(t, err)
+			} else {
+				assert.Nil(t, err)
+			}
+			assert.Equal(t, tt.want, buf.String())
+		})
+		t.Run(newMaskerTestCase(name), func(t *testing.T) {
+			m := newMasker()
+			m.RegisterMaskField("name", "filled")
+			var buf bytes.Buffer
+			w := NewJSONMaskWriter(&buf, m)
+			var err error
+			for _, s := range tt.writes {
+				if _, err = w.Write([]byte(s)); err != nil {
+					break
+				}
+			}
+			if tt.wantErr {
+				assert.NotNil(t, err)
+			} else {
+				assert.Nil(t, err)
+			}
+			assert.Equal(t, tt.want, buf.String())
+		})
+	}
+}
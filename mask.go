@@ -1,26 +1,39 @@
 package mask
 
 import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	crand "crypto/rand"
 	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/base64"
 	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
 	"math"
 	"math/rand"
+	"net"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
+	"unicode"
 	"unicode/utf8"
+	"unsafe"
 
 	"reflect"
 )
 
 func init() {
-	defaultMasker = NewMasker()
-	defaultMasker.RegisterMaskStringFunc(MaskTypeFilled, defaultMasker.MaskFilledString)
-	defaultMasker.RegisterMaskStringFunc(MaskTypeFixed, defaultMasker.MaskFixedString)
-	defaultMasker.RegisterMaskStringFunc(MaskTypeHash, defaultMasker.MaskHashString)
-	defaultMasker.RegisterMaskIntFunc(MaskTypeRandom, defaultMasker.MaskRandomInt)
-	defaultMasker.RegisterMaskFloat64Func(MaskTypeRandom, defaultMasker.MaskRandomFloat64)
-	defaultMasker.RegisterMaskAnyFunc(MaskTypeZero, defaultMasker.MaskZero)
+	defaultMasker = NewMasker(WithRegisteredDefaults())
+	// the default masker uses the global source of math/rand for compatibility
+	defaultMasker.rand = nil
 }
 
 // Tag name of the field in the structure when masking
@@ -28,13 +41,92 @@ const TagName = "mask"
 
 const maskChar = "*"
 
+const redactText = "[REDACTED]"
+
+// Options that can follow the mask type in a tag, separated by commas.
+// Only the elements after the first comma that are an option name, alone or followed by ":", are taken as options,
+// and the other elements stay in the argument of the mask type.
+const (
+	// TagOptionSlice applies the mask only to the elements of a slice or array in the index range [start, end).
+	// `mask:"filled,slice:0.2"` masks the first two elements. If end is omitted, the range extends to the last element.
+	TagOptionSlice = "slice"
+	// TagOptionKeys applies the mask given as its argument to the int or string keys of a map.
+	// `mask:"filled,keys:random100"` masks the values with "filled" and the int keys with "random100",
+	// and `mask:"filled,keys"` masks both the values and the keys with "filled".
+	// Since keys must stay unique, an entry whose masked key collides with an earlier one is dropped.
+	TagOptionKeys = "keys"
+)
+
+var tagOptions = []string{TagOptionSlice, TagOptionKeys}
+
+// structDirectiveDefault is set in the tag of a blank field to give the default mask of the untagged string fields in the struct.
+const structDirectiveDefault = "default:"
+
+// tagPrefixCallback is the prefix of a tag that calls a callback registered with RegisterMaskCallback.
+const tagPrefixCallback = "cb:"
+
+// tagPrefixPolicy is the prefix of a tag that refers to a policy registered with RegisterPolicy.
+const tagPrefixPolicy = "policy:"
+
 // Default tag that can be specified as a mask
 const (
-	MaskTypeFilled = "filled"
-	MaskTypeFixed  = "fixed"
-	MaskTypeRandom = "random"
-	MaskTypeHash   = "hash"
-	MaskTypeZero   = "zero"
+	MaskTypeFilled     = "filled"
+	MaskTypeFixed      = "fixed"
+	MaskTypeRandom     = "random"
+	MaskTypeHash       = "hash"
+	MaskTypeHMAC       = "hmac"
+	MaskTypeZero       = "zero"
+	MaskTypeIPPort     = "ipport"
+	MaskTypeEncrypt    = "encrypt"
+	MaskTypePEM        = "pem"
+	MaskTypeKVPairs    = "kvpairs"
+	MaskTypeStrFilled  = "strfilled"
+	MaskTypeToken      = "token"
+	MaskTypeNumStr     = "numstr"
+	MaskTypeGeoJSON    = "geojson"
+	MaskTypeWidth      = "width"
+	MaskTypeChecksum   = "checksum"
+	MaskTypeSQL        = "sql"
+	MaskTypeEmail      = "email"
+	MaskTypeLines      = "lines"
+	MaskTypeFPE        = "fpe"
+	MaskTypePrefix     = "prefix"
+	MaskTypeSuffix     = "suffix"
+	MaskTypeKeepPrefix = "keepprefix"
+	MaskTypeMiddle     = "middle"
+	MaskTypeHexStr     = "hexstr"
+	MaskTypeRedact     = "redact"
+	MaskTypeCookie     = "cookie"
+	// MaskTypeGroupedDigits is used like mask:"groupeddigits:4.4.4:keeplast".
+	MaskTypeGroupedDigits = "groupeddigits"
+	MaskTypeCreditCard    = "creditcard"
+	MaskTypeZip           = "zip"
+	// MaskTypeDetect is used like mask:"detect:name" with a detector registered by RegisterDetector.
+	MaskTypeDetect = "detect"
+	// MaskTypeNamedGroups is used like mask:"namedgroups:phone:area=keep,number=mask" with a regex registered by RegisterMaskRegex.
+	MaskTypeNamedGroups = "namedgroups"
+	// MaskTypePlaceholder is used like mask:"placeholder:name" with a placeholder registered with RegisterPlaceholder.
+	MaskTypePlaceholder = "placeholder"
+	// MaskTypeKeep is used like mask:"keep" to keep a value and its descendants from the mask set by SetDefaultStringMask.
+	MaskTypeKeep = "keep"
+	// MaskTypeMatrix is used like mask:"matrix:round2" to apply "zero", "roundN" or "blurX" to each element of a matrix of floats.
+	MaskTypeMatrix = "matrix"
+	// MaskTypeRare is used like mask:"rare" to mask the values that are rare in a batch masked by MaskBatch.
+	MaskTypeRare = "rare"
+	// MaskTypeBase64 is used like mask:"base64" to encode a string or a byte slice with the standard base64 encoding.
+	MaskTypeBase64 = "base64"
+	// MaskTypeBase32 is used like mask:"base32" to mask the content of a base32 encoded string and encode it again.
+	MaskTypeBase32 = "base32"
+	// MaskTypeShuffle is used like mask:"shuffle" to randomly permute the characters of a string.
+	MaskTypeShuffle = "shuffle"
+	// MaskTypeRedactTimezone is used like mask:"redacttz" to convert a time to UTC and drop its sub-second part.
+	MaskTypeRedactTimezone = "redacttz"
+	// MaskTypeDuration is used like mask:"duration:round1h" to round a time.Duration, or mask:"duration:zero" to set it to 0.
+	MaskTypeDuration = "duration"
+	// MaskTypeLaplace is used like mask:"laplace:0.5" to add Laplace noise for differential privacy with epsilon 0.5.
+	MaskTypeLaplace = "laplace"
+	// MaskTypeUUID is used like mask:"uuid" or mask:"uuid:random" to mask the hex digits of a UUID, keeping its format.
+	MaskTypeUUID = "uuid"
 )
 
 var defaultMasker *Masker
@@ -48,6 +140,93 @@ type (
 	MaskAnyFunc     func(arg string, value any) (any, error)
 )
 
+// MaskValuer is implemented by types that mask themselves.
+// A value implementing MaskValuer, or whose pointer implements it, is replaced with the result of MaskValue
+// instead of being masked field by field. The result must be assignable to the type of the value, or nil for the zero value.
+// The mask funcs specified with the tag take precedence over MaskValue, such as mask:"zero" for any type,
+// or mask:"filled" for a type whose underlying type is string.
+type MaskValuer interface {
+	MaskValue() (any, error)
+}
+
+var maskValuerType = reflect.TypeOf((*MaskValuer)(nil)).Elem()
+
+// ErrorMode determines how Mask handles the errors of the mask funcs.
+type ErrorMode int
+
+const (
+	// ErrorModeFailFast stops masking and returns the first error.
+	ErrorModeFailFast ErrorMode = iota
+	// ErrorModeCollect masks the values that failed to be masked to their zero values, continues masking the rest,
+	// and returns the masked value together with a FieldErrors holding all the errors.
+	ErrorModeCollect
+)
+
+// FieldError is an error that occurred while masking the value at Path, like "Users[0].Age".
+type FieldError struct {
+	Path string
+	Err  error
+}
+
+func (e *FieldError) Error() string {
+	return e.Path + ": " + e.Err.Error()
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// FieldErrors is the error returned in ErrorModeCollect, holding the errors of all the values that failed to be masked.
+type FieldErrors []*FieldError
+
+func (e FieldErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Unwrap returns the errors of the fields, so that errors.Is and errors.As look into each of them with Go 1.20 or later.
+func (e FieldErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
+
+// Is reports whether the error of any field matches target, so that errors.Is looks into each of them before Go 1.20.
+func (e FieldErrors) Is(target error) bool {
+	for _, err := range e {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first error of the fields that matches target, so that errors.As looks into each of them before Go 1.20.
+func (e FieldErrors) As(target any) bool {
+	for _, err := range e {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// UnwrapFunc extracts the payload from a wrapper value.
+// It returns the payload and a function that wraps the masked payload back into a new wrapper value.
+type UnwrapFunc func(value any) (payload any, rewrap func(payload any) any)
+
+// MaskCallbackFunc masks a value with any logic.
+// It receives the path to the value, like "Users[0].Name", and the value, and returns the masked value of the same type.
+type MaskCallbackFunc func(path string, value any) (any, error)
+
+// ChecksumFunc computes the check digit from the body digits of a number.
+type ChecksumFunc func(digits string) string
+
 // Mask returns an object with the mask applied to any given object.
 // The function's argument can accept any type, including pointer, map, and slice types, in addition to struct.
 // from default masker.
@@ -55,24 +234,180 @@ func Mask[T any](target T) (ret T, err error) {
 	var v any
 	v, err = defaultMasker.Mask(target)
 	if err != nil {
+		// the masked value is returned with the errors in ErrorModeCollect
+		if masked, ok := v.(T); ok {
+			return masked, err
+		}
 		return ret, err
 	}
 
 	return v.(T), nil
 }
 
+// MaskWith returns an object with the mask applied to any given object, in the same way as Mask, with the options
+// from default masker.
+func MaskWith(target any, opts ...MaskOption) (any, error) {
+	return defaultMasker.MaskWith(target, opts...)
+}
+
+// MaskTyped returns an object with the mask applied to any given object as the same type as the argument.
+// Unlike Mask, it returns an error instead of panicking if the masked value cannot be converted to the type.
+// from default masker.
+func MaskTyped[T any](target T) (T, error) {
+	return MaskTypedWith(defaultMasker, target)
+}
+
+// MaskTypedWith returns an object with the mask applied by the masker as the same type as the argument.
+// It returns the zero value and an error if the masked value cannot be converted to the type.
+func MaskTypedWith[T any](m *Masker, target T) (ret T, err error) {
+	// a nil interface has nothing to mask
+	if any(target) == nil {
+		return ret, nil
+	}
+
+	v, err := m.Mask(target)
+	if err != nil {
+		// the masked value is returned with the errors in ErrorModeCollect
+		if masked, ok := v.(T); ok {
+			return masked, err
+		}
+		return ret, err
+	}
+	masked, ok := v.(T)
+	if !ok {
+		return ret, fmt.Errorf("mask: masked value of type %T cannot be converted to %T", v, ret)
+	}
+
+	return masked, nil
+}
+
+// MaskSlice returns a copy of the slice with the mask applied to each element, without wrapping it in a struct
+// from default masker.
+func MaskSlice[T any](targets []T) ([]T, error) {
+	return MaskSliceWith(defaultMasker, targets)
+}
+
+// MaskSliceWith returns a copy of the slice with the mask applied to each element by the masker.
+// A nil slice is returned as nil. The elements are masked in the same way as the elements of a slice passed to Mask.
+func MaskSliceWith[T any](m *Masker, targets []T) ([]T, error) {
+	return MaskTypedWith(m, targets)
+}
+
+// MaskInto masks src and stores the result in the value pointed to by dst.
+// from default masker.
+func MaskInto(dst, src any) error {
+	return defaultMasker.MaskInto(dst, src)
+}
+
 // SetMaskChar changes the character used for masking
 // from default masker.
 func SetMaskChar(s string) {
 	defaultMasker.SetMaskChar(s)
 }
 
+// SetRedactText changes the text used by the "redact" mask.
+// from default masker.
+func SetRedactText(s string) {
+	defaultMasker.SetRedactText(s)
+}
+
+// SetMaxDepth limits the depth of the values to mask.
+// from default masker.
+func SetMaxDepth(depth int) {
+	defaultMasker.SetMaxDepth(depth)
+}
+
+// SetErrorMode sets how Mask handles the errors of the mask funcs
+// from default masker.
+func SetErrorMode(mode ErrorMode) {
+	defaultMasker.SetErrorMode(mode)
+}
+
+// SetHashFunc sets the hash algorithm used by the "hash" mask, such as sha256.New.
+// from default masker.
+func SetHashFunc(fn func() hash.Hash) {
+	defaultMasker.SetHashFunc(fn)
+}
+
+// SetHashSalt sets the salt prepended to the value by the "hash" mask.
+// from default masker.
+func SetHashSalt(salt string) {
+	defaultMasker.SetHashSalt(salt)
+}
+
+// SetHashKey sets the secret key used by the "hmac" mask.
+// from default masker.
+func SetHashKey(key []byte) {
+	defaultMasker.SetHashKey(key)
+}
+
+// SetRandSource sets the source of the random numbers used by the random masks.
+// If nil is passed, the global source of math/rand is used.
+// from default masker.
+func SetRandSource(src rand.Source) {
+	defaultMasker.SetRandSource(src)
+}
+
+// SetMaskRune changes the character used for masking to a single rune.
+// from default masker.
+func SetMaskRune(r rune) {
+	defaultMasker.SetMaskRune(r)
+}
+
 // MaskChar returns the current character used for masking.
 // from default masker.
 func MaskChar() string {
 	return defaultMasker.MaskChar()
 }
 
+// SetMaskCharFor changes the character used for masking by the mask type
+// from default masker.
+func SetMaskCharFor(maskType, s string) {
+	defaultMasker.SetMaskCharFor(maskType, s)
+}
+
+// SetDefaultStringMask sets the mask applied to all untagged strings and numbers.
+// from default masker.
+func SetDefaultStringMask(maskType string) {
+	defaultMasker.SetDefaultStringMask(maskType)
+}
+
+// SetInheritTag toggles passing the tag of a struct down to its untagged fields.
+// from default masker.
+func SetInheritTag(enable bool) {
+	defaultMasker.SetInheritTag(enable)
+}
+
+// SetMaskUnexported toggles copying and masking unexported fields.
+// from default masker.
+func SetMaskUnexported(enable bool) {
+	defaultMasker.SetMaskUnexported(enable)
+}
+
+// SetUseCloneMethod toggles using the Clone method of a struct as the base of its masked copy.
+// from default masker.
+func SetUseCloneMethod(enable bool) {
+	defaultMasker.SetUseCloneMethod(enable)
+}
+
+// SetEncryptionKey sets the AES key used by the "encrypt" mask.
+// from default masker.
+func SetEncryptionKey(key []byte) {
+	defaultMasker.SetEncryptionKey(key)
+}
+
+// Decrypt restores a value masked by the "encrypt" mask.
+// from default masker.
+func Decrypt(masked string) (string, error) {
+	return defaultMasker.Decrypt(masked)
+}
+
+// TokenTable returns the mapping from the tokens issued by the "token" mask to the original values.
+// from default masker.
+func TokenTable() map[string]string {
+	return defaultMasker.TokenTable()
+}
+
 // RegisterMaskField allows you to register a mask tag to be applied to the value of a struct field or map key that matches the fieldName.
 // If a mask tag is set on the struct field, it will take precedence.
 // from default masker.
@@ -80,6 +415,30 @@ func RegisterMaskField(fieldName, maskType string) {
 	defaultMasker.RegisterMaskField(fieldName, maskType)
 }
 
+// RegisterMaskFieldPath registers a mask tag to be applied to the value at the path from the root value, like "Address.PostCode".
+// from default masker.
+func RegisterMaskFieldPath(path, maskType string) {
+	defaultMasker.RegisterMaskFieldPath(path, maskType)
+}
+
+// RegisterMaskJSONField registers a mask tag to be applied to the value of a struct field whose name in the json struct tag matches jsonName.
+// from default masker.
+func RegisterMaskJSONField(jsonName, maskType string) {
+	defaultMasker.RegisterMaskJSONField(jsonName, maskType)
+}
+
+// RegisterTypeScopedField registers a mask tag to be applied to the field named fieldName only in the struct of structType.
+// from default masker.
+func RegisterTypeScopedField(structType reflect.Type, fieldName, maskType string) {
+	defaultMasker.RegisterTypeScopedField(structType, fieldName, maskType)
+}
+
+// RegisterFieldGroup registers a group of fields that are masked together with maskType when any of them is masked
+// from default masker.
+func RegisterFieldGroup(fieldNames []string, maskType string) {
+	defaultMasker.RegisterFieldGroup(fieldNames, maskType)
+}
+
 // RegisterMaskStringFunc registers a masking function for string values.
 // The function will be applied when the string set in the first argument is assigned as a tag to a field in the structure.
 // from default masker.
@@ -115,6 +474,43 @@ func RegisterMaskAnyFunc(maskType string, maskFunc MaskAnyFunc) {
 	defaultMasker.RegisterMaskAnyFunc(maskType, maskFunc)
 }
 
+// RegisterUnwrapper registers a function to extract the payload from a wrapper type.
+// The value of type t is masked by unwrapping the payload, masking it with the tag of the value, and rewrapping it.
+// from default masker.
+func RegisterUnwrapper(t reflect.Type, fn UnwrapFunc) {
+	defaultMasker.RegisterUnwrapper(t, fn)
+}
+
+// RegisterMaskTypeFunc registers a function to mask all values of type rt regardless of their tags and field names
+// from default masker.
+func RegisterMaskTypeFunc(rt reflect.Type, fn func(value any) (any, error)) {
+	defaultMasker.RegisterMaskTypeFunc(rt, fn)
+}
+
+// RegisterPolicy registers a named policy that can be referred to with a tag like mask:"policy:name".
+// from default masker.
+func RegisterPolicy(name string, handler string) {
+	defaultMasker.RegisterPolicy(name, handler)
+}
+
+// RegisterPlaceholder registers a value that replaces a value tagged like mask:"placeholder:name".
+// from default masker.
+func RegisterPlaceholder(name string, v any) {
+	defaultMasker.RegisterPlaceholder(name, v)
+}
+
+// RegisterMaskCallback registers a callback that can be called with a tag like mask:"cb:name".
+// from default masker.
+func RegisterMaskCallback(name string, fn MaskCallbackFunc) {
+	defaultMasker.RegisterMaskCallback(name, fn)
+}
+
+// RegisterChecksum registers a check digit algorithm with a name used by the checksum mask.
+// from default masker.
+func RegisterChecksum(name string, fn ChecksumFunc) {
+	defaultMasker.RegisterChecksum(name, fn)
+}
+
 // String masks the given argument string
 // from default masker.
 func String(tag, value string) (string, error) {
@@ -141,19 +537,38 @@ func Float64(tag string, value float64) (float64, error) {
 
 // structType stores the type information of a structure when caching is enabled
 type structType struct {
-	value        reflect.Value
 	structFields []reflect.StructField
+	defaultTag   string
+	clone        cloneMethod
+	// plainFields reports for each field whether its type has nothing to mask without a tag, see isPlainType.
+	plainFields []bool
 }
 
 // Masker is a struct that defines the masking process.
 type Masker struct {
 	cache             bool
+	maskUnexported    bool
+	useClone          bool
+	defaultMask       string
+	inheritTag        bool
+	maxDepth          int
+	rareThreshold     int
+	errorMode         ErrorMode
 	mu                sync.RWMutex
 	tagName           string
 	maskChar          string
+	redactText        string
+	maskRune          rune
+	maskCharMap       map[string]string
+	randMu            sync.Mutex
+	rand              *rand.Rand
 	typeToStructCache map[reflect.Type]structType
 
-	maskFieldMap map[string]string
+	maskFieldMap     map[string]string
+	maskJSONFieldMap map[string]string
+	maskFieldPathMap map[string]string
+	typeFieldMap     map[reflect.Type]map[string]string
+	fieldGroups      []fieldGroup
 
 	maskStringFuncKeys  []string
 	maskStringFuncMap   map[string]MaskStringFunc
@@ -165,18 +580,82 @@ type Masker struct {
 	maskFloat64FuncMap  map[string]MaskFloat64Func
 	maskAnyFuncKeys     []string
 	maskAnyFuncMap      map[string]MaskAnyFunc
+	// boundFuncs marks the funcs registered by WithRegisteredDefaults, which are bound to the copies made by Clone.
+	boundFuncs map[boundFunc]bool
+
+	unwrapperMap map[reflect.Type]UnwrapFunc
+	typeFuncMap  map[reflect.Type]func(value any) (any, error)
+	checksumMap  map[string]ChecksumFunc
+
+	maskCallbackMap map[string]MaskCallbackFunc
+	placeholderMap  map[string]any
+	detectorMap     map[string]Detector
+	regexMap        map[string]*regexp.Regexp
+
+	policyMu  sync.RWMutex
+	policyMap map[string]string
+
+	hashFunc      func() hash.Hash
+	hashSalt      string
+	hashKey       []byte
+	encryptionKey []byte
+	fpeKey        []byte
+
+	tokenMu sync.Mutex
+	tokens  map[string]string
+}
+
+// Option configures a Masker created by NewMasker.
+type Option func(m *Masker)
+
+// WithTagName sets the tag name used by the masker, in the same way as SetTagName.
+func WithTagName(s string) Option {
+	return func(m *Masker) {
+		m.SetTagName(s)
+	}
+}
+
+// WithMaskChar sets the character used for masking, in the same way as SetMaskChar.
+func WithMaskChar(s string) Option {
+	return func(m *Masker) {
+		m.SetMaskChar(s)
+	}
+}
+
+// WithCache enables or disables the cache of struct types, in the same way as Cache.
+func WithCache(enable bool) Option {
+	return func(m *Masker) {
+		m.Cache(enable)
+	}
+}
+
+// WithRegisteredDefaults registers the mask functions of the mask types provided by this package,
+// such as MaskTypeFilled and MaskTypeHash, as the default masker does.
+func WithRegisteredDefaults() Option {
+	return func(m *Masker) {
+		m.registerDefaults()
+	}
 }
 
-// NewMasker initializes a Masker.
-func NewMasker() *Masker {
+// NewMasker initializes a Masker and applies the options in order.
+// Without options, no mask functions are registered, so call WithRegisteredDefaults or register them yourself.
+func NewMasker(opts ...Option) *Masker {
 	m := &Masker{
-		tagName:  TagName,
-		maskChar: maskChar,
+		tagName:     TagName,
+		maskChar:    maskChar,
+		redactText:  redactText,
+		maskCharMap: make(map[string]string),
+		rand:        rand.New(rand.NewSource(time.Now().UnixNano())),
+
+		rareThreshold: 2,
 
 		cache:             true,
 		typeToStructCache: make(map[reflect.Type]structType),
 
-		maskFieldMap: make(map[string]string),
+		maskFieldMap:     make(map[string]string),
+		maskJSONFieldMap: make(map[string]string),
+		maskFieldPathMap: make(map[string]string),
+		typeFieldMap:     make(map[reflect.Type]map[string]string),
 
 		maskStringFuncKeys:  make([]string, 0, 10),
 		maskStringFuncMap:   make(map[string]MaskStringFunc),
@@ -188,15 +667,241 @@ func NewMasker() *Masker {
 		maskFloat64FuncMap:  make(map[string]MaskFloat64Func),
 		maskAnyFuncKeys:     make([]string, 0, 10),
 		maskAnyFuncMap:      make(map[string]MaskAnyFunc),
+
+		unwrapperMap: make(map[reflect.Type]UnwrapFunc),
+		typeFuncMap:  make(map[reflect.Type]func(value any) (any, error)),
+		checksumMap:  make(map[string]ChecksumFunc),
+
+		maskCallbackMap: make(map[string]MaskCallbackFunc),
+		placeholderMap:  make(map[string]any),
+		detectorMap:     make(map[string]Detector),
+		regexMap:        make(map[string]*regexp.Regexp),
+
+		policyMap: make(map[string]string),
+
+		tokens: make(map[string]string),
+	}
+	m.registerSQLNullTypes()
+	for _, opt := range opts {
+		opt(m)
 	}
 
 	return m
 }
 
+// boundFunc identifies a registered mask func by the kind of values it masks, reflect.Interface for the any funcs, and its mask type.
+type boundFunc struct {
+	kind     reflect.Kind
+	maskType string
+}
+
+// registerDefaults registers the mask functions of the mask types provided by this package, bound to m,
+// and marks them so that Clone binds them to the copy.
+func (m *Masker) registerDefaults() {
+	m.registerDefaultsTo(m)
+
+	defaults := m.defaultFuncs()
+	if m.boundFuncs == nil {
+		m.boundFuncs = make(map[boundFunc]bool)
+	}
+	for maskType := range defaults.maskStringFuncMap {
+		m.boundFuncs[boundFunc{reflect.String, maskType}] = true
+	}
+	for maskType := range defaults.maskUintFuncMap {
+		m.boundFuncs[boundFunc{reflect.Uint, maskType}] = true
+	}
+	for maskType := range defaults.maskIntFuncMap {
+		m.boundFuncs[boundFunc{reflect.Int, maskType}] = true
+	}
+	for maskType := range defaults.maskFloat64FuncMap {
+		m.boundFuncs[boundFunc{reflect.Float64, maskType}] = true
+	}
+	for maskType := range defaults.maskAnyFuncMap {
+		m.boundFuncs[boundFunc{reflect.Interface, maskType}] = true
+	}
+}
+
+// defaultFuncs returns a masker holding only the mask functions of the mask types provided by this package, bound to m.
+func (m *Masker) defaultFuncs() *Masker {
+	defaults := &Masker{
+		maskStringFuncMap:  make(map[string]MaskStringFunc),
+		maskUintFuncMap:    make(map[string]MaskUintFunc),
+		maskIntFuncMap:     make(map[string]MaskIntFunc),
+		maskFloat64FuncMap: make(map[string]MaskFloat64Func),
+		maskAnyFuncMap:     make(map[string]MaskAnyFunc),
+	}
+	m.registerDefaultsTo(defaults)
+
+	return defaults
+}
+
+// registerDefaultsTo registers the mask functions of the mask types provided by this package, bound to m, to r.
+func (m *Masker) registerDefaultsTo(r *Masker) {
+	r.RegisterMaskStringFunc(MaskTypeFilled, m.MaskFilledString)
+	r.RegisterMaskStringFunc(MaskTypeFixed, m.MaskFixedString)
+	r.RegisterMaskStringFunc(MaskTypeHash, m.MaskHashString)
+	r.RegisterMaskStringFunc(MaskTypeHMAC, m.MaskHMACString)
+	r.RegisterMaskStringFunc(MaskTypeIPPort, m.MaskIPPortString)
+	r.RegisterMaskStringFunc(MaskTypeEncrypt, m.MaskEncryptString)
+	r.RegisterMaskStringFunc(MaskTypePEM, m.MaskPEMString)
+	r.RegisterMaskStringFunc(MaskTypeKVPairs, m.MaskKVPairsString)
+	r.RegisterMaskStringFunc(MaskTypeToken, m.MaskTokenString)
+	r.RegisterMaskStringFunc(MaskTypeNumStr, m.MaskNumStrString)
+	r.RegisterMaskStringFunc(MaskTypeGeoJSON, m.MaskGeoJSONString)
+	r.RegisterMaskStringFunc(MaskTypeWidth, m.MaskWidthString)
+	r.RegisterMaskStringFunc(MaskTypeChecksum, m.MaskChecksumString)
+	r.RegisterMaskStringFunc(MaskTypeSQL, m.MaskSQLString)
+	r.RegisterMaskStringFunc(MaskTypeEmail, m.MaskEmailString)
+	r.RegisterMaskStringFunc(MaskTypeLines, m.MaskLinesString)
+	r.RegisterMaskStringFunc(MaskTypeFPE, m.MaskFPEString)
+	r.RegisterMaskStringFunc(MaskTypePrefix, m.MaskPrefixString)
+	r.RegisterMaskStringFunc(MaskTypeSuffix, m.MaskSuffixString)
+	r.RegisterMaskStringFunc(MaskTypeKeepPrefix, m.MaskKeepPrefixString)
+	r.RegisterMaskStringFunc(MaskTypeMiddle, m.MaskMiddleString)
+	r.RegisterMaskStringFunc(MaskTypeHexStr, m.MaskHexStrString)
+	r.RegisterMaskStringFunc(MaskTypeRedact, m.MaskRedactString)
+	r.RegisterMaskStringFunc(MaskTypeRedactTimezone, m.MaskRedactTimezoneString)
+	r.RegisterMaskStringFunc(MaskTypeCookie, m.MaskCookieString)
+	r.RegisterMaskStringFunc(MaskTypeGroupedDigits, m.MaskGroupedDigitsString)
+	r.RegisterMaskStringFunc(MaskTypeCreditCard, m.MaskCreditCardString)
+	r.RegisterMaskStringFunc(MaskTypeZip, m.MaskZipString)
+	r.RegisterMaskStringFunc(MaskTypeDetect, m.MaskDetectString)
+	r.RegisterMaskStringFunc(MaskTypeNamedGroups, m.MaskNamedGroupsString)
+	r.RegisterMaskStringFunc(MaskTypeBase32, m.MaskBase32String)
+	r.RegisterMaskStringFunc(MaskTypeShuffle, m.MaskShuffleString)
+	r.RegisterMaskStringFunc(MaskTypeUUID, m.MaskUUIDString)
+	r.RegisterMaskUintFunc(MaskTypeRandom, m.MaskRandomUint)
+	r.RegisterMaskIntFunc(MaskTypeRandom, m.MaskRandomInt)
+	r.RegisterMaskIntFunc(MaskTypeFPE, m.MaskFPEInt)
+	r.RegisterMaskFloat64Func(MaskTypeRandom, m.MaskRandomFloat64)
+	r.RegisterMaskFloat64Func(MaskTypeLaplace, m.MaskLaplaceFloat64)
+	r.RegisterMaskAnyFunc(MaskTypeZero, m.MaskZero)
+	r.RegisterMaskAnyFunc(MaskTypeStrFilled, m.MaskStrFilled)
+	r.RegisterMaskAnyFunc(MaskTypeRedact, m.MaskRedact)
+	r.RegisterMaskAnyFunc(MaskTypeRedactTimezone, m.MaskRedactTimezone)
+	r.RegisterMaskAnyFunc(MaskTypePlaceholder, m.MaskPlaceholder)
+	r.RegisterMaskAnyFunc(MaskTypeMatrix, m.MaskMatrix)
+	r.RegisterMaskAnyFunc(MaskTypeDuration, m.MaskDuration)
+	r.RegisterMaskAnyFunc(MaskTypeBase64, m.MaskBase64)
+}
+
+// Clone returns a copy of the masker with the same settings, registered funcs, and field rules,
+// which can be changed without affecting the original. The cache of struct types starts empty.
+// The mask funcs of this package registered by WithRegisteredDefaults are bound to the copy, so that they use the settings of the copy,
+// while the funcs registered with RegisterMaskStringFunc and the like, including the methods of a masker, are kept as they are. The copy has its own random source seeded from the original one.
+func (m *Masker) Clone() *Masker {
+	c := &Masker{
+		cache:          m.cache,
+		maskUnexported: m.maskUnexported,
+		useClone:       m.useClone,
+		defaultMask:    m.defaultMask,
+		inheritTag:     m.inheritTag,
+		maxDepth:       m.maxDepth,
+		rareThreshold:  m.rareThreshold,
+		errorMode:      m.errorMode,
+		tagName:        m.tagName,
+		maskChar:       m.maskChar,
+		redactText:     m.redactText,
+		maskRune:       m.maskRune,
+		maskCharMap:    copyMap(m.maskCharMap),
+
+		typeToStructCache: make(map[reflect.Type]structType),
+
+		maskFieldMap:     copyMap(m.maskFieldMap),
+		maskJSONFieldMap: copyMap(m.maskJSONFieldMap),
+		maskFieldPathMap: copyMap(m.maskFieldPathMap),
+		typeFieldMap:     make(map[reflect.Type]map[string]string, len(m.typeFieldMap)),
+		fieldGroups:      append([]fieldGroup(nil), m.fieldGroups...),
+
+		maskStringFuncKeys:  append([]string(nil), m.maskStringFuncKeys...),
+		maskStringFuncMap:   copyMap(m.maskStringFuncMap),
+		maskUintFuncKeys:    append([]string(nil), m.maskUintFuncKeys...),
+		maskUintFuncMap:     copyMap(m.maskUintFuncMap),
+		maskIntFuncKeys:     append([]string(nil), m.maskIntFuncKeys...),
+		maskIntFuncMap:      copyMap(m.maskIntFuncMap),
+		maskFloat64FuncKeys: append([]string(nil), m.maskFloat64FuncKeys...),
+		maskFloat64FuncMap:  copyMap(m.maskFloat64FuncMap),
+		maskAnyFuncKeys:     append([]string(nil), m.maskAnyFuncKeys...),
+		maskAnyFuncMap:      copyMap(m.maskAnyFuncMap),
+		boundFuncs:          copyMap(m.boundFuncs),
+
+		unwrapperMap: make(map[reflect.Type]UnwrapFunc, len(m.unwrapperMap)),
+		typeFuncMap:  make(map[reflect.Type]func(value any) (any, error), len(m.typeFuncMap)),
+		checksumMap:  copyMap(m.checksumMap),
+
+		maskCallbackMap: copyMap(m.maskCallbackMap),
+		placeholderMap:  copyMap(m.placeholderMap),
+		detectorMap:     copyMap(m.detectorMap),
+		regexMap:        copyMap(m.regexMap),
+
+		hashFunc:      m.hashFunc,
+		hashSalt:      m.hashSalt,
+		hashKey:       append([]byte(nil), m.hashKey...),
+		encryptionKey: append([]byte(nil), m.encryptionKey...),
+		fpeKey:        append([]byte(nil), m.fpeKey...),
+	}
+	if m.rand != nil {
+		m.randMu.Lock()
+		c.rand = rand.New(rand.NewSource(m.rand.Int63()))
+		m.randMu.Unlock()
+	}
+	for rt, fields := range m.typeFieldMap {
+		c.typeFieldMap[rt] = copyMap(fields)
+	}
+	for rt, fn := range m.unwrapperMap {
+		c.unwrapperMap[rt] = fn
+	}
+	for rt, fn := range m.typeFuncMap {
+		c.typeFuncMap[rt] = fn
+	}
+	m.policyMu.RLock()
+	c.policyMap = copyMap(m.policyMap)
+	m.policyMu.RUnlock()
+	m.tokenMu.Lock()
+	c.tokens = copyMap(m.tokens)
+	m.tokenMu.Unlock()
+	c.bindMethods()
+
+	return c
+}
+
+// bindMethods replaces the registered mask funcs marked in boundFuncs, which are bound to another masker, with those bound to m.
+func (m *Masker) bindMethods() {
+	if len(m.boundFuncs) == 0 {
+		return
+	}
+
+	defaults := m.defaultFuncs()
+	for f := range m.boundFuncs {
+		switch f.kind {
+		case reflect.String:
+			m.maskStringFuncMap[f.maskType] = defaults.maskStringFuncMap[f.maskType]
+		case reflect.Uint:
+			m.maskUintFuncMap[f.maskType] = defaults.maskUintFuncMap[f.maskType]
+		case reflect.Int:
+			m.maskIntFuncMap[f.maskType] = defaults.maskIntFuncMap[f.maskType]
+		case reflect.Float64:
+			m.maskFloat64FuncMap[f.maskType] = defaults.maskFloat64FuncMap[f.maskType]
+		case reflect.Interface:
+			m.maskAnyFuncMap[f.maskType] = defaults.maskAnyFuncMap[f.maskType]
+		}
+	}
+}
+
+func copyMap[K comparable, V any](src map[K]V) map[K]V {
+	dst := make(map[K]V, len(src))
+	for k, v := range src {
+		dst[k] = v
+	}
+	return dst
+}
+
 // SetTagName allows you to change the tag name from "mask" to something else.
+// The cache of struct types is cleared, as it holds the "default:" directives read with the tag name.
 func (m *Masker) SetTagName(s string) {
-	if s != "" {
+	if s != "" && s != m.tagName {
 		m.tagName = s
+		m.ClearCache()
 	}
 }
 
@@ -205,78 +910,456 @@ func (m *Masker) SetMaskChar(s string) {
 	m.maskChar = s
 }
 
-// Cache can be toggled to cache the type information of the struct.
-// default true
-func (m *Masker) Cache(enable bool) {
-	m.cache = enable
+// SetRedactText changes the text used by the "redact" mask.
+// default "[REDACTED]"
+func (m *Masker) SetRedactText(s string) {
+	m.redactText = s
 }
 
-// MaskChar returns the current character used for masking.
-func (m *Masker) MaskChar() string {
-	return m.maskChar
+// SetMaxDepth limits the depth of the values to mask, and Mask returns an error for values nested deeper than the limit.
+// A pointer and the value it points to, and a slice or map and its elements, count as separate levels.
+// The masking walks the values recursively, so the limit can also keep an untrusted, deeply nested value
+// from growing the goroutine stack without bound. If depth is 0 or less, there is no limit.
+// default 0
+func (m *Masker) SetMaxDepth(depth int) {
+	m.maxDepth = depth
 }
 
-func (m *Masker) getTag(tag, key string) string {
-	if tag != "" {
-		return tag
-	}
-	return m.maskFieldMap[key]
+// SetErrorMode sets how Mask handles the errors of the mask funcs.
+// In ErrorModeCollect, a value that fails to be masked is set to its zero value, the rest are masked,
+// and the masked value is returned together with a FieldErrors holding the error of each value with its path.
+// default ErrorModeFailFast
+func (m *Masker) SetErrorMode(mode ErrorMode) {
+	m.errorMode = mode
 }
 
-// RegisterMaskStringFunc registers a masking function for string values.
-// The function will be applied when the string set in the first argument is assigned as a tag to a field in the structure.
-func (m *Masker) RegisterMaskStringFunc(maskType string, maskFunc MaskStringFunc) {
-	if _, ok := m.maskStringFuncMap[maskType]; !ok {
-		m.maskStringFuncKeys = append(m.maskStringFuncKeys, maskType)
-	}
-	m.maskStringFuncMap[maskType] = maskFunc
+// SetHashFunc sets the hash algorithm used by the "hash" mask, such as sha256.New.
+// If nil is passed, sha1 is used.
+func (m *Masker) SetHashFunc(fn func() hash.Hash) {
+	m.hashFunc = fn
 }
 
-// RegisterMaskUintFunc registers a masking function for uint values.
-// The function will be applied when the uint slice set in the first argument is assigned as a tag to a field in the structure.
-func (m *Masker) RegisterMaskUintFunc(maskType string, maskFunc MaskUintFunc) {
-	if _, ok := m.maskUintFuncMap[maskType]; !ok {
-		m.maskUintFuncKeys = append(m.maskUintFuncKeys, maskType)
+// SetHashSalt sets the salt used by the "hash" mask.
+// The salt is prepended to the value before hashing, so the same value hashes differently with a different salt.
+// default "" (no salt)
+func (m *Masker) SetHashSalt(salt string) {
+	m.hashSalt = salt
+}
+
+// SetHashKey sets the secret key used by the "hmac" mask.
+func (m *Masker) SetHashKey(key []byte) {
+	m.hashKey = append([]byte(nil), key...)
+}
+
+// SetRandSource sets the source of the random numbers used by the random masks.
+// Each Masker created by NewMasker has its own source, so setting a seeded source gives reproducible output
+// without changing the global source of math/rand. If nil is passed, the global source is used.
+func (m *Masker) SetRandSource(src rand.Source) {
+	m.randMu.Lock()
+	defer m.randMu.Unlock()
+	if src == nil {
+		m.rand = nil
+		return
 	}
-	m.maskUintFuncMap[maskType] = maskFunc
+	m.rand = rand.New(src)
 }
 
-// RegisterMaskIntFunc registers a masking function for int values.
-// The function will be applied when the string set in the first argument is assigned as a tag to a field in the structure.
-func (m *Masker) RegisterMaskIntFunc(maskType string, maskFunc MaskIntFunc) {
-	if _, ok := m.maskIntFuncMap[maskType]; !ok {
-		m.maskIntFuncKeys = append(m.maskIntFuncKeys, maskType)
+// randIntn and randFloat64 lock the source, as rand.Rand is not safe for concurrent use.
+func (m *Masker) randIntn(n int) int {
+	m.randMu.Lock()
+	defer m.randMu.Unlock()
+	if m.rand == nil {
+		return rand.Intn(n)
 	}
-	m.maskIntFuncMap[maskType] = maskFunc
+	return m.rand.Intn(n)
 }
 
-// RegisterMaskFloat64Func registers a masking function for float64 values.
-// The function will be applied when the string set in the first argument is assigned as a tag to a field in the structure.
-func (m *Masker) RegisterMaskFloat64Func(maskType string, maskFunc MaskFloat64Func) {
-	if _, ok := m.maskFloat64FuncMap[maskType]; !ok {
-		m.maskFloat64FuncKeys = append(m.maskFloat64FuncKeys, maskType)
+func (m *Masker) randShuffle(n int, swap func(i, j int)) {
+	m.randMu.Lock()
+	defer m.randMu.Unlock()
+	if m.rand == nil {
+		rand.Shuffle(n, swap)
+		return
 	}
-	m.maskFloat64FuncMap[maskType] = maskFunc
+	m.rand.Shuffle(n, swap)
 }
 
-// RegisterMaskAnyFunc registers a masking function that can be applied to any type.
-// The function will be applied when the string set in the first argument is assigned as a tag to a field in the structure.
-func (m *Masker) RegisterMaskAnyFunc(maskType string, maskFunc MaskAnyFunc) {
-	if _, ok := m.maskAnyFuncMap[maskType]; !ok {
-		m.maskAnyFuncKeys = append(m.maskAnyFuncKeys, maskType)
+func (m *Masker) randFloat64() float64 {
+	m.randMu.Lock()
+	defer m.randMu.Unlock()
+	if m.rand == nil {
+		return rand.Float64()
 	}
-	m.maskAnyFuncMap[maskType] = maskFunc
+	return m.rand.Float64()
 }
 
-// RegisterMaskField allows you to register a mask tag to be applied to the value of a struct field or map key that matches the fieldName.
-// If a mask tag is set on the struct field, it will take precedence.
-func (m *Masker) RegisterMaskField(fieldName, maskType string) {
-	m.maskFieldMap[fieldName] = maskType
+// SetMaskRune changes the character used for masking to a single rune.
+// Unlike SetMaskChar, it cannot be set to a string of several runes.
+// When set, it takes precedence over the character set by SetMaskChar. Passing 0 unsets it.
+func (m *Masker) SetMaskRune(r rune) {
+	m.maskRune = r
+}
+
+// Cache can be toggled to cache the type information of the struct.
+// default true
+func (m *Masker) Cache(enable bool) {
+	m.cache = enable
+}
+
+// ClearCache removes the cached type information of all structs to release the memory.
+// The field rules and mask funcs are not cached, so they apply to the structs masked before they are registered.
+func (m *Masker) ClearCache() {
+	m.mu.Lock()
+	m.typeToStructCache = make(map[reflect.Type]structType)
+	m.mu.Unlock()
+}
+
+// CacheLen returns the number of struct types whose information is cached.
+func (m *Masker) CacheLen() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.typeToStructCache)
+}
+
+// SetDefaultStringMask sets the mask applied to all strings and numbers that have no tag and match no field rule,
+// including those in nested structs, slices, and map values, so that nothing is left unmasked unless it is allowed explicitly.
+// A value tagged with mask:"keep" (MaskTypeKeep) and everything in it are kept from the default mask,
+// but the tags and field rules in it still apply. For example, MaskTypeRedact replaces strings with "[REDACTED]" and numbers with 0.
+// default "" (no default mask)
+func (m *Masker) SetDefaultStringMask(maskType string) {
+	m.defaultMask = maskType
+}
+
+// SetInheritTag toggles passing the tag of a struct down to its untagged fields.
+// When enabled, the tag of a struct field, or the "default:" directive of the struct if it has one,
+// applies to all untagged fields of the struct, and so on to the untagged fields of nested structs.
+// A tag on a descendant field overrides the inherited one.
+// default false
+func (m *Masker) SetInheritTag(enable bool) {
+	m.inheritTag = enable
+}
+
+// SetMaskUnexported toggles copying and masking unexported fields, including those of embedded structs.
+// Unexported fields are read and written through the unsafe package, so enable this only for types you own,
+// typically when the Masker is used in the same package as the masked types.
+// default false
+func (m *Masker) SetMaskUnexported(enable bool) {
+	m.maskUnexported = enable
+}
+
+// SetUseCloneMethod toggles using the Clone method of a struct as the base of its masked copy.
+// If a struct has a Clone method returning a copy of it as the same type or a pointer to it, the masked copy starts from the result of Clone,
+// so that its unexported fields are kept, and the untagged fields that hold no structs, interfaces, or maps are not copied again.
+// The other exported fields are masked as usual. Clone must return a deep copy that shares no mutable state, such as maps, with the original.
+// Enable this only for types whose unexported fields may appear in the masked value.
+// default false
+func (m *Masker) SetUseCloneMethod(enable bool) {
+	m.useClone = enable
+}
+
+// MaskChar returns the current character used for masking.
+func (m *Masker) MaskChar() string {
+	if m.maskRune != 0 {
+		return string(m.maskRune)
+	}
+	return m.maskChar
+}
+
+// SetMaskCharFor changes the character used for masking by the mask type of this package, such as MaskTypeFilled,
+// so that different mask types can use different characters. It takes precedence over SetMaskChar and SetMaskRune.
+// Passing an empty string unsets it.
+func (m *Masker) SetMaskCharFor(maskType, s string) {
+	if s == "" {
+		delete(m.maskCharMap, maskType)
+		return
+	}
+	m.maskCharMap[maskType] = s
+}
+
+// maskCharFor returns the character used for masking by the mask type.
+func (m *Masker) maskCharFor(maskType string) string {
+	if s, ok := m.maskCharMap[maskType]; ok {
+		return s
+	}
+	return m.MaskChar()
+}
+
+// TokenTable returns a copy of the mapping from the tokens issued by MaskTokenString to the original values.
+func (m *Masker) TokenTable() map[string]string {
+	m.tokenMu.Lock()
+	defer m.tokenMu.Unlock()
+
+	table := make(map[string]string, len(m.tokens))
+	for value, token := range m.tokens {
+		table[token] = value
+	}
+
+	return table
+}
+
+// SetEncryptionKey sets the AES key used by the "encrypt" mask.
+// The key must be 16, 24, or 32 bytes long to select AES-128, AES-192, or AES-256.
+func (m *Masker) SetEncryptionKey(key []byte) {
+	m.encryptionKey = append([]byte(nil), key...)
+}
+
+// getFieldTag returns the mask tag of a field of the struct of type rt.
+func (m *Masker) getFieldTag(rt reflect.Type, tag string, field reflect.StructField) string {
+	if tag == "" {
+		tag = m.typeFieldMap[rt][field.Name]
+	}
+	if tag == "" && len(m.maskJSONFieldMap) > 0 {
+		tag = m.maskJSONFieldMap[jsonFieldName(field)]
+	}
+	return m.getTag(tag, field.Name)
+}
+
+// jsonFieldName returns the name of the field in the json struct tag, or the Go field name if it has none.
+func jsonFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return field.Name
+	}
+	return name
+}
+
+func (m *Masker) getTag(tag, key string) string {
+	if tag == "" {
+		tag = m.maskFieldMap[key]
+	}
+	return m.resolvePolicy(tag)
+}
+
+// resolvePolicy replaces a "policy:name" tag with the mask tag registered for the policy.
+// The tag options following the policy name are kept.
+func (m *Masker) resolvePolicy(tag string) string {
+	if !strings.HasPrefix(tag, tagPrefixPolicy) {
+		return tag
+	}
+	name, options, ok := strings.Cut(tag[len(tagPrefixPolicy):], ",")
+	m.policyMu.RLock()
+	handler := m.policyMap[name]
+	m.policyMu.RUnlock()
+	if ok && handler != "" {
+		return handler + "," + options
+	}
+	return handler
+}
+
+// RegisterMaskStringFunc registers a masking function for string values.
+// The function will be applied when the string set in the first argument is assigned as a tag to a field in the structure.
+func (m *Masker) RegisterMaskStringFunc(maskType string, maskFunc MaskStringFunc) {
+	if _, ok := m.maskStringFuncMap[maskType]; !ok {
+		m.maskStringFuncKeys = addMaskType(m.maskStringFuncKeys, maskType)
+	}
+	m.maskStringFuncMap[maskType] = maskFunc
+	delete(m.boundFuncs, boundFunc{reflect.String, maskType})
+}
+
+// RegisterMaskUintFunc registers a masking function for uint values.
+// The function will be applied when the uint slice set in the first argument is assigned as a tag to a field in the structure.
+func (m *Masker) RegisterMaskUintFunc(maskType string, maskFunc MaskUintFunc) {
+	if _, ok := m.maskUintFuncMap[maskType]; !ok {
+		m.maskUintFuncKeys = addMaskType(m.maskUintFuncKeys, maskType)
+	}
+	m.maskUintFuncMap[maskType] = maskFunc
+	delete(m.boundFuncs, boundFunc{reflect.Uint, maskType})
+}
+
+// RegisterMaskIntFunc registers a masking function for int values.
+// The function will be applied when the string set in the first argument is assigned as a tag to a field in the structure.
+func (m *Masker) RegisterMaskIntFunc(maskType string, maskFunc MaskIntFunc) {
+	if _, ok := m.maskIntFuncMap[maskType]; !ok {
+		m.maskIntFuncKeys = addMaskType(m.maskIntFuncKeys, maskType)
+	}
+	m.maskIntFuncMap[maskType] = maskFunc
+	delete(m.boundFuncs, boundFunc{reflect.Int, maskType})
+}
+
+// RegisterMaskFloat64Func registers a masking function for float64 values.
+// The function will be applied when the string set in the first argument is assigned as a tag to a field in the structure.
+func (m *Masker) RegisterMaskFloat64Func(maskType string, maskFunc MaskFloat64Func) {
+	if _, ok := m.maskFloat64FuncMap[maskType]; !ok {
+		m.maskFloat64FuncKeys = addMaskType(m.maskFloat64FuncKeys, maskType)
+	}
+	m.maskFloat64FuncMap[maskType] = maskFunc
+	delete(m.boundFuncs, boundFunc{reflect.Float64, maskType})
+}
+
+// RegisterMaskAnyFunc registers a masking function that can be applied to any type.
+// The function will be applied when the string set in the first argument is assigned as a tag to a field in the structure.
+func (m *Masker) RegisterMaskAnyFunc(maskType string, maskFunc MaskAnyFunc) {
+	if _, ok := m.maskAnyFuncMap[maskType]; !ok {
+		m.maskAnyFuncKeys = addMaskType(m.maskAnyFuncKeys, maskType)
+	}
+	m.maskAnyFuncMap[maskType] = maskFunc
+	delete(m.boundFuncs, boundFunc{reflect.Interface, maskType})
+}
+
+// addMaskType adds maskType to the keys of the registered mask types.
+// It is inserted before the mask types that are a prefix of it, so that a tag matches the longest registered mask type,
+// like "redacttz" is not matched as "redact" with "tz" as the argument.
+func addMaskType(keys []string, maskType string) []string {
+	for i, key := range keys {
+		if strings.HasPrefix(maskType, key) {
+			keys = append(keys, "")
+			copy(keys[i+1:], keys[i:])
+			keys[i] = maskType
+			return keys
+		}
+	}
+	return append(keys, maskType)
+}
+
+// RegisterMaskField allows you to register a mask tag to be applied to the value of a struct field or map key that matches the fieldName.
+// If a mask tag is set on the struct field, it will take precedence.
+func (m *Masker) RegisterMaskField(fieldName, maskType string) {
+	m.maskFieldMap[fieldName] = maskType
+}
+
+// RegisterMaskFieldPath registers a mask tag to be applied to the value at the path from the root value.
+// The path is the struct field names and map keys joined by dots, and slice and array indexes are omitted,
+// so "Address.PostCode" matches only the PostCode field of the Address field, and "Users.Name" matches the Name field of every element of Users.
+// If a mask tag is set on the struct field or the map, it will take precedence,
+// and a rule registered with RegisterMaskFieldPath takes precedence over the other field rules such as RegisterMaskField.
+// Registering a path makes the masking keep track of the path to each value, which costs some performance.
+// A pointer or a map reachable from several paths is masked separately for each of them, so the masked copies do not share it.
+func (m *Masker) RegisterMaskFieldPath(path, maskType string) {
+	m.maskFieldPathMap[path] = maskType
+}
+
+// RegisterMaskJSONField registers a mask tag to be applied to the value of a struct field whose name in the json struct tag matches jsonName.
+// For example, jsonName "user_id" matches a field tagged `json:"user_id,omitempty"`, and a field without a json tag matches by its Go field name.
+// If several fields of a struct share the json name, all of them are masked.
+// If a mask tag is set on the struct field, it will take precedence, and a rule registered with RegisterMaskJSONField
+// takes precedence over one registered with RegisterMaskField. Map keys are matched only by RegisterMaskField.
+func (m *Masker) RegisterMaskJSONField(jsonName, maskType string) {
+	m.maskJSONFieldMap[jsonName] = maskType
+}
+
+// RegisterTypeScopedField registers a mask tag to be applied to the field named fieldName only in the struct of structType,
+// so that a common field name like "Name" can be masked in one struct without masking it everywhere.
+// A pointer to a struct type can also be passed. The mask tag set on the struct field takes precedence,
+// and a rule registered with RegisterTypeScopedField takes precedence over one registered with RegisterMaskField.
+func (m *Masker) RegisterTypeScopedField(structType reflect.Type, fieldName, maskType string) {
+	if structType.Kind() == reflect.Ptr {
+		structType = structType.Elem()
+	}
+	fields, ok := m.typeFieldMap[structType]
+	if !ok {
+		fields = make(map[string]string)
+		m.typeFieldMap[structType] = fields
+	}
+	fields[fieldName] = maskType
+}
+
+// RegisterFieldGroup registers a group of fields that are masked together, such as a secret split across "KeyPart1" and "KeyPart2".
+// When a field of the group in a struct is masked by its mask tag or a registered field rule,
+// the other fields of the group in the same struct are masked with maskType, so that a part of the secret is not left unmasked.
+// The mask tag or the field rule of a field takes precedence over maskType.
+func (m *Masker) RegisterFieldGroup(fieldNames []string, maskType string) {
+	m.fieldGroups = append(m.fieldGroups, fieldGroup{
+		fieldNames: append([]string(nil), fieldNames...),
+		maskType:   maskType,
+	})
+}
+
+// fieldGroup is a group of fields registered with RegisterFieldGroup.
+type fieldGroup struct {
+	fieldNames []string
+	maskType   string
+}
+
+// fieldGroupTags returns the mask tags of the fields of the struct of type rt
+// that are masked because another field of their group is masked.
+func (m *Masker) fieldGroupTags(s *maskState, rt reflect.Type) map[string]string {
+	var tags map[string]string
+	for _, g := range m.fieldGroups {
+		masked := false
+		for i := 0; i < rt.NumField(); i++ {
+			field := rt.Field(i)
+			if containsString(g.fieldNames, field.Name) &&
+				m.getFieldTag(rt, m.getPathTag(s, field.Tag.Get(m.tagName), field.Name), field) != "" {
+				masked = true
+				break
+			}
+		}
+		if !masked {
+			continue
+		}
+		if tags == nil {
+			tags = make(map[string]string)
+		}
+		for _, name := range g.fieldNames {
+			if _, ok := tags[name]; !ok {
+				tags[name] = g.maskType
+			}
+		}
+	}
+
+	return tags
+}
+
+func containsString(ss []string, s string) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// RegisterUnwrapper registers a function to extract the payload from a wrapper type.
+// The value of type t is masked by unwrapping the payload, masking it with the tag of the value, and rewrapping it.
+func (m *Masker) RegisterUnwrapper(t reflect.Type, fn UnwrapFunc) {
+	m.unwrapperMap[t] = fn
+}
+
+// RegisterMaskTypeFunc registers a function to mask all values of type rt, wherever they are.
+// A pointer type is registered as its element type, and the pointers to the values are followed as usual.
+// The function takes precedence over the struct tags, RegisterMaskField and the other field rules,
+// so the values of the type are masked in the same way regardless of their tags and field names.
+// The function must return a value assignable to rt, or nil for the zero value.
+func (m *Masker) RegisterMaskTypeFunc(rt reflect.Type, fn func(value any) (any, error)) {
+	for rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+	m.typeFuncMap[rt] = fn
+}
+
+// RegisterPolicy registers a named policy that can be referred to with a tag like mask:"policy:name".
+// The fields tagged with the policy are masked with the mask tag passed to handler, such as "filled4".
+// Registering the same name again changes the mask of all the fields tagged with the policy.
+// The fields tagged with an unregistered policy are not masked.
+func (m *Masker) RegisterPolicy(name string, handler string) {
+	m.policyMu.Lock()
+	defer m.policyMu.Unlock()
+	m.policyMap[name] = handler
+}
+
+// RegisterPlaceholder registers a value that replaces a value tagged like mask:"placeholder:name",
+// such as a fixed struct replacing a whole struct held in an any field.
+// The value must be assignable to the type of the tagged values. It is not copied, so the values it refers to are shared.
+func (m *Masker) RegisterPlaceholder(name string, v any) {
+	m.placeholderMap[name] = v
+}
+
+// RegisterMaskCallback registers a callback that can be called with a tag like mask:"cb:name".
+// The callback receives the path to the value and the value, so it can mask values that do not fit the other mask functions.
+func (m *Masker) RegisterMaskCallback(name string, fn MaskCallbackFunc) {
+	m.maskCallbackMap[name] = fn
+}
+
+// RegisterChecksum registers a check digit algorithm with a name used by the checksum mask.
+// The function receives the body digits and returns the check digit.
+func (m *Masker) RegisterChecksum(name string, fn ChecksumFunc) {
+	m.checksumMap[name] = fn
 }
 
 // String masks the given argument string
 func (m *Masker) String(tag, value string) (string, error) {
 	if tag != "" {
+		tag = trimTagOptions(tag)
 		for _, mt := range m.maskStringFuncKeys {
 			if strings.HasPrefix(tag, mt) {
 				return m.maskStringFuncMap[mt](tag[len(mt):], value)
@@ -293,6 +1376,7 @@ func (m *Masker) String(tag, value string) (string, error) {
 // Uint masks the given argument uint
 func (m *Masker) Uint(tag string, value uint) (uint, error) {
 	if tag != "" {
+		tag = trimTagOptions(tag)
 		for _, mt := range m.maskUintFuncKeys {
 			if strings.HasPrefix(tag, mt) {
 				return m.maskUintFuncMap[mt](tag[len(mt):], value)
@@ -309,6 +1393,7 @@ func (m *Masker) Uint(tag string, value uint) (uint, error) {
 // Int masks the given argument int
 func (m *Masker) Int(tag string, value int) (int, error) {
 	if tag != "" {
+		tag = trimTagOptions(tag)
 		for _, mt := range m.maskIntFuncKeys {
 			if strings.HasPrefix(tag, mt) {
 				return m.maskIntFuncMap[mt](tag[len(mt):], value)
@@ -325,6 +1410,7 @@ func (m *Masker) Int(tag string, value int) (int, error) {
 // Float64 masks the given argument float64
 func (m *Masker) Float64(tag string, value float64) (float64, error) {
 	if tag != "" {
+		tag = trimTagOptions(tag)
 		for _, mt := range m.maskFloat64FuncKeys {
 			if strings.HasPrefix(tag, mt) {
 				return m.maskFloat64FuncMap[mt](tag[len(mt):], value)
@@ -340,10 +1426,17 @@ func (m *Masker) Float64(tag string, value float64) (float64, error) {
 
 func (m *Masker) maskAny(tag string, value any) (bool, any, error) {
 	if tag != "" {
+		tag = trimTagOptions(tag)
 		for _, mt := range m.maskAnyFuncKeys {
 			if strings.HasPrefix(tag, mt) {
 				v, err := m.maskAnyFuncMap[mt](tag[len(mt):], value)
-				return true, v, err
+				if err != nil {
+					return true, reflect.Zero(reflect.TypeOf(value)).Interface(), err
+				}
+				if reflect.TypeOf(v) != reflect.TypeOf(value) {
+					return true, reflect.Zero(reflect.TypeOf(value)).Interface(), fmt.Errorf("mask: %s returned %T for a value of type %T", mt, v, value)
+				}
+				return true, v, nil
 			}
 		}
 	}
@@ -352,11 +1445,26 @@ func (m *Masker) maskAny(tag string, value any) (bool, any, error) {
 }
 
 func (m *Masker) maskAnyValue(tag string, value reflect.Value) (bool, reflect.Value, error) {
+	// A nil interface has nothing to mask and stays nil.
+	if value.Kind() == reflect.Interface && value.IsNil() {
+		return false, value, nil
+	}
 	if tag != "" {
+		tag = trimTagOptions(tag)
 		for _, mt := range m.maskAnyFuncKeys {
 			if strings.HasPrefix(tag, mt) {
 				v, err := m.maskAnyFuncMap[mt](tag[len(mt):], value.Interface())
-				return true, reflect.ValueOf(v), err
+				if err != nil {
+					return true, reflect.Value{}, err
+				}
+				rv := reflect.ValueOf(v)
+				if !rv.IsValid() {
+					return true, reflect.Zero(value.Type()), nil
+				}
+				if !rv.Type().AssignableTo(value.Type()) {
+					return true, reflect.Value{}, fmt.Errorf("mask: %s returned %T for a value of type %s", mt, v, value.Type())
+				}
+				return true, rv, nil
 			}
 		}
 	}
@@ -364,7 +1472,79 @@ func (m *Masker) maskAnyValue(tag string, value reflect.Value) (bool, reflect.Va
 	return false, value, nil
 }
 
+// cutTagOption removes the option named name from the tag.
+// It returns the tag without the option, the option's argument following ":", and whether the option was found.
+func cutTagOption(tag, name string) (string, string, bool) {
+	if strings.IndexByte(tag, ',') < 0 {
+		return tag, "", false
+	}
+
+	elems := strings.Split(tag, ",")
+	for i := 1; i < len(elems); i++ {
+		if elems[i] == name || strings.HasPrefix(elems[i], name+":") {
+			arg := strings.TrimPrefix(elems[i][len(name):], ":")
+			return strings.Join(append(elems[:i:i], elems[i+1:]...), ","), arg, true
+		}
+	}
+
+	return tag, "", false
+}
+
+// isTagOption reports whether an element of a tag separated by commas is a tag option, such as "slice:1".
+func isTagOption(elem string) bool {
+	for _, name := range tagOptions {
+		if elem == name || strings.HasPrefix(elem, name+":") {
+			return true
+		}
+	}
+	return false
+}
+
+// trimTagOptions returns the tag without any options, leaving only the mask type and its argument.
+func trimTagOptions(tag string) string {
+	if strings.IndexByte(tag, ',') < 0 {
+		return tag
+	}
+	for _, name := range tagOptions {
+		tag, _, _ = cutTagOption(tag, name)
+	}
+
+	return tag
+}
+
+// sliceRange returns the tag without the slice option and the index range of the elements to be masked.
+func sliceRange(tag string, length int) (string, int, int, error) {
+	tag, arg, ok := cutTagOption(tag, TagOptionSlice)
+	if !ok {
+		return tag, 0, length, nil
+	}
+
+	first, last, hasLast := strings.Cut(arg, ".")
+	start, err := strconv.Atoi(first)
+	if err != nil {
+		return "", 0, 0, err
+	}
+	end := length
+	if hasLast {
+		if end, err = strconv.Atoi(last); err != nil {
+			return "", 0, 0, err
+		}
+	}
+	if start < 0 || end < start {
+		return "", 0, 0, fmt.Errorf("mask: invalid slice range %q", arg)
+	}
+	if start > length {
+		start = length
+	}
+	if end > length {
+		end = length
+	}
+
+	return tag, start, end, nil
+}
+
 // MaskFilledString masks the string length of the value with the same length.
+// The length is the number of runes, so a multibyte string is masked with one mask character per character.
 // If you pass a number like "2" to arg, it masks with the length of the number.(**)
 func (m *Masker) MaskFilledString(arg, value string) (string, error) {
 	if arg != "" {
@@ -373,37 +1553,875 @@ func (m *Masker) MaskFilledString(arg, value string) (string, error) {
 			return "", err
 		}
 
-		return strings.Repeat(m.MaskChar(), count), nil
+		return strings.Repeat(m.maskCharFor(MaskTypeFilled), count), nil
+	}
+
+	return strings.Repeat(m.maskCharFor(MaskTypeFilled), utf8.RuneCountInString(value)), nil
+}
+
+// MaskPrefixString keeps the first characters of a string and masks the rest.
+// For example, if you pass "3" to arg, "ABCDEFGH" is converted to "ABC*****".
+// If the string has that many characters or fewer, it is returned as is, and if arg is empty or "0", the whole string is masked.
+func (m *Masker) MaskPrefixString(arg, value string) (string, error) {
+	n := 0
+	if arg != "" {
+		var err error
+		if n, err = strconv.Atoi(arg); err != nil {
+			return "", err
+		}
+		if n < 0 {
+			return "", fmt.Errorf("mask: invalid prefix length %d", n)
+		}
+	}
+
+	runes := []rune(value)
+	if n >= len(runes) {
+		return value, nil
+	}
+
+	return string(runes[:n]) + strings.Repeat(m.maskCharFor(MaskTypePrefix), len(runes)-n), nil
+}
+
+// MaskSuffixString keeps the last characters of a string and masks the rest.
+// For example, if you pass "4" to arg, "1234567890123456" is converted to "************3456".
+// If the string has that many characters or fewer, it is returned as is.
+func (m *Masker) MaskSuffixString(arg, value string) (string, error) {
+	n, err := strconv.Atoi(arg)
+	if err != nil {
+		return "", err
+	}
+	if n < 0 {
+		return "", fmt.Errorf("mask: invalid suffix length %d", n)
+	}
+
+	runes := []rune(value)
+	if n >= len(runes) {
+		return value, nil
+	}
+
+	return strings.Repeat(m.maskCharFor(MaskTypeSuffix), len(runes)-n) + string(runes[len(runes)-n:]), nil
+}
+
+// MaskZipString generalizes a postal code by masking its last characters, so that the codes in the same area are masked to the same value.
+// If you pass a number like "3" or ":3" to arg, the last 3 letters and digits are masked (default 3),
+// and separators such as hyphens and spaces are kept: "123-4567" → "123-4***", "SW1A 1AA" → "SW1A ***".
+func (m *Masker) MaskZipString(arg, value string) (string, error) {
+	n := 3
+	if arg = strings.TrimPrefix(arg, ":"); arg != "" {
+		var err error
+		n, err = strconv.Atoi(arg)
+		if err != nil {
+			return "", err
+		}
+		if n < 0 {
+			return "", fmt.Errorf("mask: invalid zip length %d", n)
+		}
+	}
+
+	runes := []rune(value)
+	start := len(runes)
+	for start > 0 && n > 0 {
+		start--
+		if unicode.IsLetter(runes[start]) || unicode.IsDigit(runes[start]) {
+			n--
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString(string(runes[:start]))
+	for _, r := range runes[start:] {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			sb.WriteString(m.maskCharFor(MaskTypeZip))
+		} else {
+			sb.WriteRune(r)
+		}
+	}
+
+	return sb.String(), nil
+}
+
+// MaskMiddleString keeps the characters at both ends of a string and masks the middle.
+// The numbers of characters to keep at the head and the tail are passed to arg like "2.2",
+// for example, "09012345678" is converted to "09*******78". If the string has that many characters or fewer, it is returned as is.
+func (m *Masker) MaskMiddleString(arg, value string) (string, error) {
+	head, tail, err := parseDottedArg(arg)
+	if err != nil {
+		return "", err
+	}
+	if head < 0 || tail < 0 {
+		return "", fmt.Errorf("mask: invalid middle argument %q", arg)
+	}
+
+	runes := []rune(value)
+	if head+tail >= len(runes) {
+		return value, nil
+	}
+
+	return string(runes[:head]) + strings.Repeat(m.maskCharFor(MaskTypeMiddle), len(runes)-head-tail) + string(runes[len(runes)-tail:]), nil
+}
+
+// MaskKeepPrefixString keeps a known prefix of a string and masks the rest in the same way as MaskFilledString.
+// The prefix is passed to arg, like "keepprefix:usr_". If the string does not start with the prefix, the whole string is masked.
+func (m *Masker) MaskKeepPrefixString(arg, value string) (string, error) {
+	prefix := strings.TrimPrefix(arg, ":")
+	if prefix == "" || !strings.HasPrefix(value, prefix) {
+		return m.MaskFilledString("", value)
+	}
+
+	rest, err := m.MaskFilledString("", value[len(prefix):])
+	if err != nil {
+		return "", err
 	}
 
-	return strings.Repeat(m.MaskChar(), utf8.RuneCountInString(value)), nil
+	return prefix + rest, nil
 }
 
 // MaskFixedString masks with a fixed length (8 characters).
 func (m *Masker) MaskFixedString(arg, value string) (string, error) {
-	return strings.Repeat(m.MaskChar(), 8), nil
+	return strings.Repeat(m.maskCharFor(MaskTypeFixed), 8), nil
 }
 
-// MaskHashString masks and hashes (sha1) a string.
+// MaskHashString masks and hashes (sha1 by default) a string.
+// The hash algorithm can be changed with SetHashFunc, and the salt set by SetHashSalt is prepended to the value.
 func (m *Masker) MaskHashString(arg, value string) (string, error) {
-	hash := sha1.Sum(([]byte)(value))
-	return hex.EncodeToString(hash[:]), nil
+	newHash := m.hashFunc
+	if newHash == nil {
+		newHash = sha1.New
+	}
+	h := newHash()
+	h.Write([]byte(m.hashSalt))
+	h.Write([]byte(value))
+	return hex.EncodeToString(h.Sum(nil)), nil
 }
 
-// MaskRandomInt converts an integer (int) into a random number.
-// For example, if you pass "100" as the arg, it sets a random number in the range of 0-99.
-func (m *Masker) MaskRandomInt(arg string, value int) (int, error) {
-	n, err := strconv.Atoi(arg)
+// MaskHMACString masks and returns a string as the hex encoded HMAC with the key set by SetHashKey.
+// Unlike MaskHashString, the value cannot be guessed by hashing candidates without the key.
+// The hash algorithm set by SetHashFunc is used, or sha256 if it is not set.
+// An error is returned if the key is not set.
+func (m *Masker) MaskHMACString(arg, value string) (string, error) {
+	if len(m.hashKey) == 0 {
+		return "", errors.New("mask: hash key is not set")
+	}
+	newHash := m.hashFunc
+	if newHash == nil {
+		newHash = sha256.New
+	}
+	h := hmac.New(newHash, m.hashKey)
+	h.Write([]byte(value))
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// MaskLinesString masks each line of a multiline string in the same way as MaskFilledString.
+// The number of lines and the newlines ("\n" and "\r\n") are kept.
+func (m *Masker) MaskLinesString(arg, value string) (string, error) {
+	lines := strings.Split(value, "\n")
+	for i, line := range lines {
+		cr := strings.HasSuffix(line, "\r")
+		masked, err := m.MaskFilledString(arg, strings.TrimSuffix(line, "\r"))
+		if err != nil {
+			return "", err
+		}
+		if cr {
+			masked += "\r"
+		}
+		lines[i] = masked
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
+
+// MaskEmailString masks the local part of an email address while keeping the domain.
+// The first character of the local part is kept, and if you pass a number like "2" to arg, that many characters are kept.
+// The address is split on the last "@". If the value has no "@", it is masked in the same way as MaskFilledString.
+func (m *Masker) MaskEmailString(arg, value string) (string, error) {
+	keep := 1
+	if arg != "" {
+		var err error
+		if keep, err = strconv.Atoi(arg); err != nil {
+			return "", err
+		}
+		if keep < 0 {
+			return "", fmt.Errorf("mask: invalid email length %d", keep)
+		}
+	}
+
+	at := strings.LastIndex(value, "@")
+	if at < 0 {
+		return m.MaskFilledString("", value)
+	}
+
+	local := []rune(value[:at])
+	if keep > len(local) {
+		keep = len(local)
+	}
+
+	return string(local[:keep]) + strings.Repeat(m.maskCharFor(MaskTypeEmail), len(local)-keep) + value[at:], nil
+}
+
+// MaskIPPortString masks the host of a "host:port" string while keeping the port.
+// For IPv4 the last octet is masked, and for IPv6 the interface identifier (the last 64 bits) is masked.
+// If the value cannot be parsed, it is masked in the same way as MaskFilledString.
+func (m *Masker) MaskIPPortString(arg, value string) (string, error) {
+	host, port, err := net.SplitHostPort(value)
 	if err != nil {
-		return 0, err
+		return m.MaskFilledString("", value)
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return m.MaskFilledString("", value)
 	}
 
-	return rand.Intn(n), nil
+	return net.JoinHostPort(m.maskIP(ip), port), nil
 }
 
-// MaskRandomFloat64 converts a float64 to a random number.
+func (m *Masker) maskIP(ip net.IP) string {
+	if ip4 := ip.To4(); ip4 != nil {
+		last := strconv.Itoa(int(ip4[3]))
+		return fmt.Sprintf("%d.%d.%d.%s", ip4[0], ip4[1], ip4[2], strings.Repeat(m.maskCharFor(MaskTypeIPPort), len(last)))
+	}
+
+	ip6 := ip.To16()
+	groups := make([]string, 0, 8)
+	for i := 0; i < 8; i += 2 {
+		groups = append(groups, strconv.FormatUint(uint64(ip6[i])<<8|uint64(ip6[i+1]), 16))
+	}
+	for i := 0; i < 4; i++ {
+		groups = append(groups, m.maskCharFor(MaskTypeIPPort))
+	}
+
+	return strings.Join(groups, ":")
+}
+
+// MaskEncryptString encrypts a string with AES-GCM using the key set by SetEncryptionKey,
+// and returns the nonce and ciphertext encoded in base64. The value can be restored with Decrypt.
+// A random nonce is generated for every call, so the same value is encrypted to a different string each time.
+// This keeps the encryption secure, but the masked values cannot be compared or joined with each other.
+func (m *Masker) MaskEncryptString(arg, value string) (string, error) {
+	aead, err := m.newAEAD()
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := crand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(value), nil)
+
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt restores a value masked by MaskEncryptString.
+func (m *Masker) Decrypt(masked string) (string, error) {
+	aead, err := m.newAEAD()
+	if err != nil {
+		return "", err
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(masked)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return "", errors.New("mask: encrypted value is too short")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", err
+	}
+
+	return string(plaintext), nil
+}
+
+func (m *Masker) newAEAD() (cipher.AEAD, error) {
+	if len(m.encryptionKey) == 0 {
+		return nil, errors.New("mask: encryption key is not set")
+	}
+	block, err := aes.NewCipher(m.encryptionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// MaskPEMString masks the base64 body of PEM blocks while keeping the "-----BEGIN ...-----" and "-----END ...-----" lines.
+// Each line of the body is masked with the same number of characters, so the shape of the block is kept.
+// If the value does not contain a PEM block, it is masked in the same way as MaskFilledString.
+func (m *Masker) MaskPEMString(arg, value string) (string, error) {
+	if !strings.Contains(value, "-----BEGIN ") {
+		return m.MaskFilledString("", value)
+	}
+
+	lines := strings.Split(value, "\n")
+	inBlock := false
+	for i, line := range lines {
+		body := strings.TrimSuffix(line, "\r")
+		switch {
+		case strings.HasPrefix(body, "-----BEGIN "):
+			inBlock = true
+		case strings.HasPrefix(body, "-----END "):
+			inBlock = false
+		case inBlock:
+			lines[i] = strings.Repeat(m.maskCharFor(MaskTypePEM), utf8.RuneCountInString(body)) + line[len(body):]
+		}
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
+
+// MaskKVPairsString masks the values of the given keys in a string of space-separated "key=value" pairs, such as a log line.
+// The keys are passed to arg as a comma-separated list, e.g. "kvpairs:password,token".
+// The values of the other keys are kept as they are.
+// A key named after a tag option, such as "slice" or "keys", is rejected since the tag would take it as the option.
+func (m *Masker) MaskKVPairsString(arg, value string) (string, error) {
+	keys := strings.Split(strings.TrimPrefix(arg, ":"), ",")
+	for _, key := range keys {
+		if isTagOption(key) {
+			return "", fmt.Errorf("mask: kvpairs key %q is a tag option", key)
+		}
+	}
+	pairs := strings.Split(value, " ")
+	for i, pair := range pairs {
+		k, v, ok := strings.Cut(pair, "=")
+		if !ok {
+			continue
+		}
+		for _, key := range keys {
+			if k == key {
+				pairs[i] = k + "=" + strings.Repeat(m.maskCharFor(MaskTypeKVPairs), utf8.RuneCountInString(v))
+				break
+			}
+		}
+	}
+
+	return strings.Join(pairs, " "), nil
+}
+
+// MaskTokenString replaces a string with a token like "tok_1".
+// Tokens are numbered sequentially in the order the distinct values are seen, and the same value always gets the same token.
+// The original values can be looked up with TokenTable.
+func (m *Masker) MaskTokenString(arg, value string) (string, error) {
+	m.tokenMu.Lock()
+	defer m.tokenMu.Unlock()
+
+	token, ok := m.tokens[value]
+	if !ok {
+		token = "tok_" + strconv.Itoa(len(m.tokens)+1)
+		m.tokens[value] = token
+	}
+
+	return token, nil
+}
+
+// MaskNumStrString masks the digits of a number formatted with group separators, such as "1,234,567" or "1.234.567",
+// keeping the first group and the separators: "1,234,567" → "1,***,***".
+// If the number has no separators, all digits are masked.
+func (m *Masker) MaskNumStrString(arg, value string) (string, error) {
+	first := strings.IndexFunc(value, isDigit)
+	grouped := first >= 0 && strings.IndexFunc(value[first:], func(r rune) bool { return !isDigit(r) }) >= 0
+
+	var sb strings.Builder
+	leading, seenDigit := grouped, false
+	for _, r := range value {
+		if !isDigit(r) {
+			if seenDigit {
+				leading = false
+			}
+			sb.WriteRune(r)
+			continue
+		}
+		seenDigit = true
+		if leading {
+			sb.WriteRune(r)
+		} else {
+			sb.WriteString(m.maskCharFor(MaskTypeNumStr))
+		}
+	}
+
+	return sb.String(), nil
+}
+
+// MaskHexStrString masks the hex digits of a hex string such as a color code or a hash,
+// keeping a "#" or "0x" prefix and the other characters: "#1A2B3C" → "#******", "0xdead:beef" → "0x****:****".
+func (m *Masker) MaskHexStrString(arg, value string) (string, error) {
+	prefix := ""
+	switch {
+	case strings.HasPrefix(value, "#"):
+		prefix = "#"
+	case strings.HasPrefix(value, "0x"), strings.HasPrefix(value, "0X"):
+		prefix = value[:2]
+	}
+
+	var sb strings.Builder
+	sb.WriteString(prefix)
+	for _, r := range value[len(prefix):] {
+		if isHexDigit(r) {
+			sb.WriteString(m.maskCharFor(MaskTypeHexStr))
+		} else {
+			sb.WriteRune(r)
+		}
+	}
+
+	return sb.String(), nil
+}
+
+// MaskUUIDString masks the hex digits of a UUID in the canonical 8-4-4-4-12 format, keeping the dashes and the version and variant digits,
+// so that the result is still a valid UUID: "550e8400-e29b-41d4-a716-446655440000" → "00000000-0000-4000-a000-000000000000".
+// If you pass "random" to arg, the digits are replaced with random ones in the case of the original.
+// If the string is not a well-formed UUID, it is masked in the same way as MaskFilledString.
+func (m *Masker) MaskUUIDString(arg, value string) (string, error) {
+	random := false
+	switch strings.TrimPrefix(arg, ":") {
+	case "":
+	case "random":
+		random = true
+	default:
+		return "", fmt.Errorf("mask: unknown %s option %q", MaskTypeUUID, arg)
+	}
+	if !isUUID(value) {
+		return m.MaskFilledString("", value)
+	}
+
+	digits := "0123456789abcdef"
+	if strings.ContainsAny(value, "ABCDEF") {
+		digits = "0123456789ABCDEF"
+	}
+	b := []byte(value)
+	for i := range b {
+		// the dashes, the version digit, and the variant digit are kept
+		if b[i] == '-' || i == 14 || i == 19 {
+			continue
+		}
+		if !random {
+			b[i] = '0'
+			continue
+		}
+		b[i] = digits[m.randIntn(len(digits))]
+	}
+
+	return string(b), nil
+}
+
+// isUUID reports whether s is a UUID in the canonical 8-4-4-4-12 format of hex digits.
+func isUUID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for i, r := range s {
+		switch i {
+		case 8, 13, 18, 23:
+			if r != '-' {
+				return false
+			}
+		default:
+			if !isHexDigit(r) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// MaskCookieString masks the value of a cookie in a Set-Cookie header string, keeping the name and the attributes:
+// "session=abc123; Path=/; HttpOnly" → "session=***; Path=/; HttpOnly".
+// The value is replaced with a fixed number of mask characters so that its length is not revealed.
+// If you pass a number like "5" to arg, it masks with that number of characters (default 3).
+func (m *Masker) MaskCookieString(arg, value string) (string, error) {
+	count := 3
+	if arg != "" {
+		var err error
+		if count, err = strconv.Atoi(arg); err != nil {
+			return "", err
+		}
+		if count < 0 {
+			return "", fmt.Errorf("mask: invalid cookie length %d", count)
+		}
+	}
+
+	pair, attrs, hasAttrs := strings.Cut(value, ";")
+	masked := strings.Repeat(m.maskCharFor(MaskTypeCookie), count)
+	if name, _, ok := strings.Cut(pair, "="); ok {
+		masked = name + "=" + masked
+	}
+	if hasAttrs {
+		masked += ";" + attrs
+	}
+
+	return masked, nil
+}
+
+// MaskGroupedDigitsString masks the digits of an ID made of groups of digits, such as an Aadhaar number or an SSN,
+// keeping the separators in place. The arg is like ":4.4.4:keeplast", with the sizes of the groups separated by dots
+// and an option "keeplast" or "keepfirst" to keep the digits of the last or the first group:
+// "1234 5678 9012" → "**** **** 9012" and "123-45-6789" with ":3.2.4:keeplast" → "***-**-6789".
+// If the sizes are omitted, like "::keeplast", the groups are the runs of digits between the separators.
+// If the number of digits does not match the sizes, all digits are masked.
+func (m *Masker) MaskGroupedDigitsString(arg, value string) (string, error) {
+	sizesArg, option, _ := strings.Cut(strings.TrimPrefix(arg, ":"), ":")
+	if option != "" && option != "keeplast" && option != "keepfirst" {
+		return "", fmt.Errorf("mask: unknown %s option %q", MaskTypeGroupedDigits, option)
+	}
+
+	// groups holds the group index of each digit
+	var groups []int
+	if sizesArg != "" {
+		for i, s := range strings.Split(sizesArg, ".") {
+			size, err := strconv.Atoi(s)
+			if err != nil {
+				return "", err
+			}
+			for j := 0; j < size; j++ {
+				groups = append(groups, i)
+			}
+		}
+	} else {
+		group, inGroup := -1, false
+		for _, r := range value {
+			if isDigit(r) && !inGroup {
+				group++
+			}
+			inGroup = isDigit(r)
+			if inGroup {
+				groups = append(groups, group)
+			}
+		}
+	}
+	var digits int
+	for _, r := range value {
+		if isDigit(r) {
+			digits++
+		}
+	}
+	keep := -1
+	if digits == len(groups) && len(groups) > 0 {
+		switch option {
+		case "keeplast":
+			keep = groups[len(groups)-1]
+		case "keepfirst":
+			keep = groups[0]
+		}
+	}
+
+	var sb strings.Builder
+	i := 0
+	for _, r := range value {
+		if !isDigit(r) {
+			sb.WriteRune(r)
+			continue
+		}
+		if keep >= 0 && groups[i] == keep {
+			sb.WriteRune(r)
+		} else {
+			sb.WriteString(m.maskCharFor(MaskTypeGroupedDigits))
+		}
+		i++
+	}
+
+	return sb.String(), nil
+}
+
+// MaskCreditCardString masks the digits of a card number except the last four,
+// keeping the spaces, dashes and other non-digit characters in place.
+// "4111 1111 1111 1111" → "**** **** **** 1111"
+func (m *Masker) MaskCreditCardString(arg, value string) (string, error) {
+	var digits int
+	for _, r := range value {
+		if isDigit(r) {
+			digits++
+		}
+	}
+
+	var sb strings.Builder
+	for _, r := range value {
+		if isDigit(r) && digits > 4 {
+			sb.WriteString(m.maskCharFor(MaskTypeCreditCard))
+			digits--
+			continue
+		}
+		sb.WriteRune(r)
+	}
+
+	return sb.String(), nil
+}
+
+func isHexDigit(r rune) bool {
+	return isDigit(r) || 'a' <= r && r <= 'f' || 'A' <= r && r <= 'F'
+}
+
+func isDigit(r rune) bool {
+	return '0' <= r && r <= '9'
+}
+
+// MaskGeoJSONString rounds all coordinates in a GeoJSON string to reduce their precision.
+// If you pass a number like "2" to arg, coordinates are rounded to that number of decimal places (default 2).
+// The keys of the masked GeoJSON are sorted. If the value is not a JSON object, it is masked in the same way as MaskFilledString.
+func (m *Masker) MaskGeoJSONString(arg, value string) (string, error) {
+	precision := 2
+	if arg != "" {
+		var err error
+		if precision, err = strconv.Atoi(arg); err != nil {
+			return "", err
+		}
+	}
+
+	var geo map[string]any
+	if err := json.Unmarshal([]byte(value), &geo); err != nil {
+		return m.MaskFilledString("", value)
+	}
+	b, err := json.Marshal(roundCoordinates(geo, false, math.Pow10(precision)))
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
+
+// roundCoordinates rounds the numbers in the "coordinates" and "bbox" members of decoded GeoJSON.
+func roundCoordinates(v any, inCoordinates bool, scale float64) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			v[key] = roundCoordinates(value, inCoordinates || key == "coordinates" || key == "bbox", scale)
+		}
+	case []any:
+		for i, value := range v {
+			v[i] = roundCoordinates(value, inCoordinates, scale)
+		}
+	case float64:
+		if inCoordinates {
+			return math.Round(v*scale) / scale
+		}
+	}
+
+	return v
+}
+
+// MaskWidthString truncates a string to the display width passed to arg.
+// Wide characters such as CJK characters count as 2 columns, and the others count as 1.
+// For example, "width6" truncates "ABうさぎ" to "ABうさ".
+func (m *Masker) MaskWidthString(arg, value string) (string, error) {
+	width, err := strconv.Atoi(arg)
+	if err != nil {
+		return "", err
+	}
+
+	total := 0
+	for i, r := range value {
+		total += runeWidth(r)
+		if total > width {
+			return value[:i], nil
+		}
+	}
+
+	return value, nil
+}
+
+// wideRanges is the ranges of the characters displayed with a width of 2 columns,
+// such as East Asian Wide and Fullwidth characters.
+var wideRanges = [][2]rune{
+	{0x1100, 0x115F},   // Hangul Jamo
+	{0x2E80, 0x303E},   // CJK Radicals, Kangxi Radicals, CJK Symbols and Punctuation
+	{0x3041, 0x33FF},   // Hiragana, Katakana, Bopomofo, Hangul Compatibility Jamo, CJK Compatibility
+	{0x3400, 0x4DBF},   // CJK Unified Ideographs Extension A
+	{0x4E00, 0x9FFF},   // CJK Unified Ideographs
+	{0xA000, 0xA4CF},   // Yi Syllables and Radicals
+	{0xAC00, 0xD7A3},   // Hangul Syllables
+	{0xF900, 0xFAFF},   // CJK Compatibility Ideographs
+	{0xFE30, 0xFE4F},   // CJK Compatibility Forms
+	{0xFF00, 0xFF60},   // Fullwidth Forms
+	{0xFFE0, 0xFFE6},   // Fullwidth Signs
+	{0x1F300, 0x1F64F}, // Miscellaneous Symbols and Pictographs, Emoticons
+	{0x1F900, 0x1F9FF}, // Supplemental Symbols and Pictographs
+	{0x20000, 0x2FFFD}, // CJK Unified Ideographs Extension B and later
+	{0x30000, 0x3FFFD},
+}
+
+func runeWidth(r rune) int {
+	for _, wr := range wideRanges {
+		if r < wr[0] {
+			break
+		}
+		if r <= wr[1] {
+			return 2
+		}
+	}
+
+	return 1
+}
+
+// MaskSQLString masks the string and number literals in a SQL query while keeping the shape of the query.
+// For example, "WHERE email='a@b.com' AND age > 20" is converted to "WHERE email='****' AND age > ****".
+// Double-quoted identifiers are kept as is.
+func (m *Masker) MaskSQLString(arg, value string) (string, error) {
+	literal := strings.Repeat(m.maskCharFor(MaskTypeSQL), 4)
+
+	var sb strings.Builder
+	for i := 0; i < len(value); {
+		c := value[i]
+		switch {
+		case c == '\'':
+			// Skip to the closing quote, treating '' as an escaped quote.
+			j := i + 1
+			for j < len(value) {
+				if value[j] == '\'' {
+					if j+1 < len(value) && value[j+1] == '\'' {
+						j += 2
+						continue
+					}
+					break
+				}
+				j++
+			}
+			sb.WriteString("'" + literal + "'")
+			i = j + 1
+		case c == '"':
+			j := strings.IndexByte(value[i+1:], '"')
+			if j < 0 {
+				sb.WriteString(value[i:])
+				i = len(value)
+				continue
+			}
+			sb.WriteString(value[i : i+j+2])
+			i += j + 2
+		case isDigit(rune(c)) && (i == 0 || !isSQLIdentChar(value[i-1])):
+			j := i
+			for j < len(value) && (isDigit(rune(value[j])) || value[j] == '.') {
+				j++
+			}
+			sb.WriteString(literal)
+			i = j
+		case isSQLIdentChar(c):
+			// Write the whole identifier so that digits inside it are not treated as numbers.
+			j := i
+			for j < len(value) && isSQLIdentChar(value[j]) {
+				j++
+			}
+			sb.WriteString(value[i:j])
+			i = j
+		default:
+			sb.WriteByte(c)
+			i++
+		}
+	}
+
+	return sb.String(), nil
+}
+
+func isSQLIdentChar(c byte) bool {
+	return c == '_' || c == '$' || '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || c >= utf8.RuneSelf
+}
+
+// MaskChecksumString randomizes the digits of a number with a trailing check digit and recomputes the check digit.
+// Pass the name of the algorithm registered with RegisterChecksum to arg, like "checksum:luhn".
+// The characters other than digits are kept in place.
+// If the value has fewer than 2 digits, it is masked in the same way as MaskFilledString.
+func (m *Masker) MaskChecksumString(arg, value string) (string, error) {
+	name := strings.TrimPrefix(arg, ":")
+	fn, ok := m.checksumMap[name]
+	if !ok {
+		return "", fmt.Errorf("mask: checksum %q is not registered", name)
+	}
+
+	digits := make([]byte, 0, len(value))
+	for _, r := range value {
+		if isDigit(r) {
+			digits = append(digits, byte('0'+m.randIntn(10)))
+		}
+	}
+	if len(digits) < 2 {
+		return m.MaskFilledString("", value)
+	}
+	body := string(digits[:len(digits)-1])
+
+	var sb strings.Builder
+	i := 0
+	for _, r := range value {
+		if !isDigit(r) {
+			sb.WriteRune(r)
+			continue
+		}
+		if i < len(body) {
+			sb.WriteByte(body[i])
+		} else {
+			sb.WriteString(fn(body))
+		}
+		i++
+	}
+
+	return sb.String(), nil
+}
+
+// MaskRandomInt converts an integer (int) into a random number.
+// For example, if you pass "100" as the arg, it sets a random number in the range of 0-99.
+func (m *Masker) MaskRandomInt(arg string, value int) (int, error) {
+	n, err := strconv.Atoi(arg)
+	if err != nil {
+		return 0, err
+	}
+
+	return m.randIntn(n), nil
+}
+
+// MaskRandomUint converts a uint to a random number in the same way as MaskRandomInt.
+// For example, if you pass "100" to arg, it sets a random number in the range of 0 to 99.
+func (m *Masker) MaskRandomUint(arg string, value uint) (uint, error) {
+	n, err := strconv.Atoi(arg)
+	if err != nil {
+		return 0, err
+	}
+
+	return uint(m.randIntn(n)), nil
+}
+
+// MaskRandomFloat64 converts a float64 to a random number.
 // For example, if you pass "100.3" to arg, it sets a random number in the range of 0.000 to 99.999.
 func (m *Masker) MaskRandomFloat64(arg string, value float64) (float64, error) {
+	i, d, err := parseDottedArg(arg)
+	if err != nil {
+		return 0, err
+	}
+
+	dd := math.Pow10(d)
+	x := float64(int(m.randFloat64() * float64(i) * dd))
+
+	return x / dd, nil
+}
+
+// MaskLaplaceFloat64 adds Laplace noise to a float64 for differential privacy.
+// The arg is the privacy budget epsilon and optionally the sensitivity, like "laplace:0.5" or "laplace:0.5:10",
+// and the noise is drawn from the Laplace distribution with the scale of sensitivity/epsilon. The sensitivity is 1 by default.
+// The random source set by SetRandSource is used, so the noise is reproducible with a fixed seed.
+func (m *Masker) MaskLaplaceFloat64(arg string, value float64) (float64, error) {
+	epsArg, sensArg, hasSens := strings.Cut(strings.TrimPrefix(arg, ":"), ":")
+	eps, err := strconv.ParseFloat(epsArg, 64)
+	if err != nil {
+		return 0, err
+	}
+	sens := 1.0
+	if hasSens {
+		if sens, err = strconv.ParseFloat(sensArg, 64); err != nil {
+			return 0, err
+		}
+	}
+	if eps <= 0 || sens < 0 {
+		return 0, fmt.Errorf("mask: invalid %s epsilon %v or sensitivity %v", MaskTypeLaplace, eps, sens)
+	}
+
+	// inverse transform sampling, with u in (-0.5, 0.5) so that the logarithm is finite
+	u := m.randFloat64() - 0.5
+	for u == -0.5 {
+		u = m.randFloat64() - 0.5
+	}
+	noise := -sens / eps * math.Copysign(math.Log(1-2*math.Abs(u)), u)
+
+	return value + noise, nil
+}
+
+// parseDottedArg parses an argument of two integers separated by a dot, like "100.3".
+// If the second integer is omitted, it is 0.
+func parseDottedArg(arg string) (int, int, error) {
 	var (
 		i, d int
 		err  error
@@ -411,57 +2429,813 @@ func (m *Masker) MaskRandomFloat64(arg string, value float64) (float64, error) {
 	digits := strings.Split(arg, ".")
 	if len(digits) > 0 {
 		if i, err = strconv.Atoi(digits[0]); err != nil {
-			return 0, err
+			return 0, 0, err
+		}
+	}
+	if len(digits) == 2 {
+		if d, err = strconv.Atoi(digits[1]); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	return i, d, nil
+}
+
+// MaskZero converts the value to its type's zero value.
+func (m *Masker) MaskZero(arg string, value any) (any, error) {
+	if value == nil {
+		return nil, nil
+	}
+	return reflect.Zero(reflect.TypeOf(value)).Interface(), nil
+}
+
+// MaskMatrix applies an operation passed to arg to each element of a matrix of floats, such as [][]float64.
+// The operation is one of "zero", "roundN" which rounds to N decimal places (default 0),
+// and "blurX" which adds a random noise in the range of -X to X, like ":round2" or ":blur0.5".
+// Nil rows are kept as they are, and a value other than a slice or array of floats, or of slices of them, is an error.
+func (m *Masker) MaskMatrix(arg string, value any) (any, error) {
+	op, err := m.matrixOp(strings.TrimPrefix(arg, ":"))
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		return nil, nil
+	}
+
+	if matrix, ok := value.([][]float64); ok {
+		if matrix == nil {
+			return matrix, nil
+		}
+		masked := make([][]float64, len(matrix))
+		for i, row := range matrix {
+			if row == nil {
+				continue
+			}
+			masked[i] = make([]float64, len(row))
+			for j, x := range row {
+				masked[i][j] = op(x)
+			}
+		}
+		return masked, nil
+	}
+
+	rv := reflect.ValueOf(value)
+	if !isFloatMatrix(rv.Type()) {
+		return nil, fmt.Errorf("mask: %s mask cannot be applied to %T", MaskTypeMatrix, value)
+	}
+
+	return maskFloats(rv, op).Interface(), nil
+}
+
+// matrixOp returns the operation of MaskMatrix applied to each element.
+func (m *Masker) matrixOp(arg string) (func(float64) float64, error) {
+	switch {
+	case arg == "zero":
+		return func(float64) float64 { return 0 }, nil
+	case strings.HasPrefix(arg, "round"):
+		d := 0
+		if s := arg[len("round"):]; s != "" {
+			var err error
+			if d, err = strconv.Atoi(s); err != nil {
+				return nil, err
+			}
+		}
+		p := math.Pow10(d)
+		return func(x float64) float64 { return math.Round(x*p) / p }, nil
+	case strings.HasPrefix(arg, "blur"):
+		r, err := strconv.ParseFloat(arg[len("blur"):], 64)
+		if err != nil {
+			return nil, err
+		}
+		return func(x float64) float64 { return x + (m.randFloat64()*2-1)*r }, nil
+	}
+
+	return nil, fmt.Errorf("mask: unknown %s operation %q", MaskTypeMatrix, arg)
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// MaskDuration applies "zero" or "roundX" to a time.Duration, or to each element of a slice or an array of them.
+// For example, "round1m" rounds 1h2m31s to 1h3m0s. X is in the format of time.ParseDuration.
+// It cannot be applied to other integer types, such as int64, even though time.Duration is an int64.
+func (m *Masker) MaskDuration(arg string, value any) (any, error) {
+	op, err := durationOp(strings.TrimPrefix(arg, ":"))
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		return nil, nil
+	}
+
+	rv := reflect.ValueOf(value)
+	if !isDurations(rv.Type()) {
+		return nil, fmt.Errorf("mask: %s mask cannot be applied to %T", MaskTypeDuration, value)
+	}
+
+	return maskDurations(rv, op).Interface(), nil
+}
+
+func durationOp(arg string) (func(time.Duration) time.Duration, error) {
+	switch {
+	case arg == "zero":
+		return func(time.Duration) time.Duration { return 0 }, nil
+	case strings.HasPrefix(arg, "round"):
+		d, err := time.ParseDuration(arg[len("round"):])
+		if err != nil {
+			return nil, err
+		}
+		return func(x time.Duration) time.Duration { return x.Round(d) }, nil
+	}
+
+	return nil, fmt.Errorf("mask: unknown %s operation %q", MaskTypeDuration, arg)
+}
+
+// isDurations reports whether rt is time.Duration, or a slice, an array, or a pointer of them.
+func isDurations(rt reflect.Type) bool {
+	switch rt.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Ptr:
+		return isDurations(rt.Elem())
+	}
+	return rt == durationType
+}
+
+func maskDurations(rv reflect.Value, op func(time.Duration) time.Duration) reflect.Value {
+	switch rv.Kind() {
+	case reflect.Int64:
+		return reflect.ValueOf(op(time.Duration(rv.Int())))
+	case reflect.Ptr:
+		if rv.IsNil() {
+			return rv
+		}
+		v := reflect.New(rv.Type().Elem())
+		v.Elem().Set(maskDurations(rv.Elem(), op))
+		return v
+	case reflect.Slice:
+		if rv.IsNil() {
+			return rv
+		}
+		v := reflect.MakeSlice(rv.Type(), rv.Len(), rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			v.Index(i).Set(maskDurations(rv.Index(i), op))
+		}
+		return v
+	default: // reflect.Array
+		v := reflect.New(rv.Type()).Elem()
+		for i := 0; i < rv.Len(); i++ {
+			v.Index(i).Set(maskDurations(rv.Index(i), op))
+		}
+		return v
+	}
+}
+
+// isFloatMatrix reports whether the type is a slice or array of floats, or of slices or arrays of them.
+func isFloatMatrix(rt reflect.Type) bool {
+	switch rt.Kind() {
+	case reflect.Slice, reflect.Array:
+		switch rt.Elem().Kind() {
+		case reflect.Float32, reflect.Float64:
+			return true
+		}
+		return isFloatMatrix(rt.Elem())
+	}
+	return false
+}
+
+// maskFloats returns a copy of the slice or array of floats with op applied to each element.
+func maskFloats(rv reflect.Value, op func(float64) float64) reflect.Value {
+	switch rv.Kind() {
+	case reflect.Float32, reflect.Float64:
+		v := reflect.New(rv.Type()).Elem()
+		v.SetFloat(op(rv.Float()))
+		return v
+	case reflect.Slice:
+		if rv.IsNil() {
+			return rv
+		}
+		v := reflect.MakeSlice(rv.Type(), rv.Len(), rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			v.Index(i).Set(maskFloats(rv.Index(i), op))
+		}
+		return v
+	default: // reflect.Array
+		v := reflect.New(rv.Type()).Elem()
+		for i := 0; i < rv.Len(); i++ {
+			v.Index(i).Set(maskFloats(rv.Index(i), op))
+		}
+		return v
+	}
+}
+
+// MaskBase64 encodes a string or a byte slice with the standard base64 encoding, so that the value is obscured but its length can still be told.
+// The encoded text is returned in the type of the value: a []byte is converted to a []byte holding the text, which is shown as a string by fmt.
+// A nil slice stays nil, and an empty one is converted to an empty one.
+func (m *Masker) MaskBase64(arg string, value any) (any, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	rv := reflect.ValueOf(value)
+	switch {
+	case rv.Kind() == reflect.String:
+		s := base64.StdEncoding.EncodeToString([]byte(rv.String()))
+		return reflect.ValueOf(s).Convert(rv.Type()).Interface(), nil
+	case rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() == reflect.Uint8:
+		if rv.IsNil() {
+			return value, nil
+		}
+		b := make([]byte, base64.StdEncoding.EncodedLen(rv.Len()))
+		base64.StdEncoding.Encode(b, rv.Bytes())
+		return reflect.ValueOf(b).Convert(rv.Type()).Interface(), nil
+	}
+
+	return nil, fmt.Errorf("mask: %s mask cannot be applied to %T", MaskTypeBase64, value)
+}
+
+// MaskBase32String decodes a base32 encoded string, fills the decoded content with the mask character, and encodes it again.
+// Each decoded byte is replaced with the mask character, so the masked string has the same length as the original when the mask character is one byte.
+// The padding is kept as it is: a string without "=" is decoded and encoded without padding.
+// If the string cannot be decoded, it is masked in the same way as MaskFilledString.
+func (m *Masker) MaskBase32String(arg, value string) (string, error) {
+	enc := base32.StdEncoding
+	if !strings.Contains(value, "=") {
+		enc = enc.WithPadding(base32.NoPadding)
+	}
+	decoded, err := enc.DecodeString(value)
+	if err != nil {
+		return m.MaskFilledString("", value)
+	}
+
+	return enc.EncodeToString([]byte(strings.Repeat(m.maskCharFor(MaskTypeBase32), len(decoded)))), nil
+}
+
+// MaskShuffleString randomly permutes the characters of a string, keeping its length and the set of characters.
+// The random source set by SetRandSource is used, so the permutation is reproducible with a fixed seed.
+func (m *Masker) MaskShuffleString(arg, value string) (string, error) {
+	runes := []rune(value)
+	m.randShuffle(len(runes), func(i, j int) {
+		runes[i], runes[j] = runes[j], runes[i]
+	})
+
+	return string(runes), nil
+}
+
+// MaskRedactString replaces a string with the text set by SetRedactText, "[REDACTED]" by default.
+func (m *Masker) MaskRedactString(arg, value string) (string, error) {
+	return m.redactText, nil
+}
+
+// MaskRedact replaces a value of a string type with the text set by SetRedactText, and any other value with its type's zero value.
+// Pointers are followed, so a pointer to a string points to the text.
+func (m *Masker) MaskRedact(arg string, value any) (any, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.String:
+		s, err := m.MaskRedactString(arg, rv.String())
+		if err != nil {
+			return nil, err
+		}
+		return reflect.ValueOf(s).Convert(rv.Type()).Interface(), nil
+	case reflect.Ptr:
+		if rv.IsNil() {
+			return value, nil
+		}
+		v, err := m.MaskRedact(arg, rv.Elem().Interface())
+		if err != nil {
+			return nil, err
+		}
+		ptr := reflect.New(rv.Type().Elem())
+		if v != nil {
+			ptr.Elem().Set(reflect.ValueOf(v))
+		}
+		return ptr.Interface(), nil
+	}
+
+	return reflect.Zero(rv.Type()).Interface(), nil
+}
+
+// MaskRedactTimezone hides the location of a time by converting it to UTC and dropping its sub-second part.
+// It can be applied to a time.Time and to a string in the RFC 3339 format, such as "2024-01-02T12:04:05.123+09:00", which is converted to "2024-01-02T03:04:05Z".
+// A string that cannot be parsed is masked in the same way as MaskFilledString.
+// Pointers, slices, and arrays are followed, so that each time in them is converted.
+func (m *Masker) MaskRedactTimezone(arg string, value any) (any, error) {
+	if value == nil {
+		return nil, nil
+	}
+	if t, ok := value.(time.Time); ok {
+		return t.UTC().Truncate(time.Second), nil
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.String:
+		s, err := m.MaskRedactTimezoneString(arg, rv.String())
+		if err != nil {
+			return nil, err
+		}
+		return reflect.ValueOf(s).Convert(rv.Type()).Interface(), nil
+	case reflect.Ptr:
+		if rv.IsNil() {
+			return value, nil
+		}
+		v, err := m.MaskRedactTimezone(arg, rv.Elem().Interface())
+		if err != nil {
+			return nil, err
+		}
+		ptr := reflect.New(rv.Type().Elem())
+		ptr.Elem().Set(reflect.ValueOf(v))
+		return ptr.Interface(), nil
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			return value, nil
+		}
+		masked := reflect.New(rv.Type()).Elem()
+		if rv.Kind() == reflect.Slice {
+			masked = reflect.MakeSlice(rv.Type(), rv.Len(), rv.Len())
+		}
+		for i := 0; i < rv.Len(); i++ {
+			v, err := m.MaskRedactTimezone(arg, rv.Index(i).Interface())
+			if err != nil {
+				return nil, err
+			}
+			masked.Index(i).Set(reflect.ValueOf(v))
+		}
+		return masked.Interface(), nil
+	}
+
+	return nil, fmt.Errorf("mask: %s mask cannot be applied to %T", MaskTypeRedactTimezone, value)
+}
+
+// MaskRedactTimezoneString converts a time string in the RFC 3339 format to UTC and drops its sub-second part.
+// A string that cannot be parsed is masked in the same way as MaskFilledString.
+func (m *Masker) MaskRedactTimezoneString(arg, value string) (string, error) {
+	if value == "" {
+		return value, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return m.MaskFilledString("", value)
+	}
+
+	return t.UTC().Truncate(time.Second).Format(time.RFC3339), nil
+}
+
+// MaskPlaceholder replaces a value with the placeholder registered with RegisterPlaceholder under the name passed to arg, like "placeholder:name".
+// An error is returned if the placeholder is not registered, or if it cannot be assigned to the value.
+func (m *Masker) MaskPlaceholder(arg string, value any) (any, error) {
+	name := strings.TrimPrefix(arg, ":")
+	v, ok := m.placeholderMap[name]
+	if !ok {
+		return nil, fmt.Errorf("mask: placeholder %q is not registered", name)
+	}
+
+	return v, nil
+}
+
+// MaskStrFilled masks a byte array holding a UTF-8 string, such as [16]byte, in the same way as MaskFilledString.
+// Trailing zero bytes are treated as padding and are not masked.
+// The masked string is written back to an array of the same length: if it is longer than the array it is truncated in bytes,
+// and if it is shorter the rest of the array is padded with zero bytes.
+// A string value is masked in the same way as MaskFilledString, pointers are followed, and other values are returned as they are.
+func (m *Masker) MaskStrFilled(arg string, value any) (any, error) {
+	if s, ok := value.(string); ok {
+		return m.MaskFilledString(arg, s)
+	}
+
+	rv := reflect.ValueOf(value)
+	if rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		v, err := m.MaskStrFilled(arg, rv.Elem().Interface())
+		if err != nil {
+			return nil, err
+		}
+		ptr := reflect.New(rv.Type().Elem())
+		ptr.Elem().Set(reflect.ValueOf(v))
+		return ptr.Interface(), nil
+	}
+	if rv.Kind() != reflect.Array || rv.Type().Elem().Kind() != reflect.Uint8 {
+		return value, nil
+	}
+	b := make([]byte, rv.Len())
+	reflect.Copy(reflect.ValueOf(b), rv)
+	masked, err := m.MaskFilledString(arg, strings.TrimRight(string(b), "\x00"))
+	if err != nil {
+		return nil, err
+	}
+	rv2 := reflect.New(rv.Type()).Elem()
+	reflect.Copy(rv2, reflect.ValueOf([]byte(masked)))
+
+	return rv2.Interface(), nil
+}
+
+// Mask returns an object with the mask applied to any given object.
+// The function's argument can accept any type, including pointer, map, and slice types, in addition to struct.
+// The unexported fields of a struct are not copied, unless SetMaskUnexported or SetUseCloneMethod is enabled.
+func (m *Masker) Mask(target any) (ret any, err error) {
+	s := m.newMaskState()
+	rv, err := m.mask(s, reflect.ValueOf(target), "", reflect.Value{})
+	if err != nil {
+		return ret, err
+	}
+
+	return rv.Interface(), s.collectedError()
+}
+
+// MaskOption is an option for MaskWith.
+type MaskOption func(*maskOptions)
+
+type maskOptions struct {
+	level string
+}
+
+// WithLevel sets the level that selects the mask of the tags with levels, like mask:"filled@debug,zero@info".
+// With WithLevel("info"), the value is masked with "zero".
+func WithLevel(level string) MaskOption {
+	return func(o *maskOptions) {
+		o.level = level
+	}
+}
+
+// MaskWith returns an object with the mask applied to any given object, in the same way as Mask, with the options.
+func (m *Masker) MaskWith(target any, opts ...MaskOption) (any, error) {
+	var o maskOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	s := m.newMaskState()
+	s.level = o.level
+	rv, err := m.mask(s, reflect.ValueOf(target), "", reflect.Value{})
+	if err != nil {
+		return nil, err
+	}
+
+	return rv.Interface(), s.collectedError()
+}
+
+// MaskInto masks src and stores the result in the value pointed to by dst, without boxing it in an interface.
+// dst must be a non-nil pointer either to the type of src or of the same pointer type as src.
+// In the latter case, the value pointed to by src is masked and stored, so dst can be reused for each call:
+//
+//	var dst User
+//	err := m.MaskInto(&dst, &src)
+func (m *Masker) MaskInto(dst, src any) error {
+	dv := reflect.ValueOf(dst)
+	if dv.Kind() != reflect.Ptr || dv.IsNil() {
+		return fmt.Errorf("mask: MaskInto requires a non-nil pointer, got %T", dst)
+	}
+	sv := reflect.ValueOf(src)
+	if !sv.IsValid() {
+		return fmt.Errorf("mask: cannot mask %T into %T", src, dst)
+	}
+
+	switch {
+	case dv.Type().Elem() == sv.Type():
+	case dv.Type() == sv.Type():
+		if sv.IsNil() {
+			dv.Elem().Set(reflect.Zero(dv.Type().Elem()))
+			return nil
+		}
+		sv = sv.Elem()
+	default:
+		return fmt.Errorf("mask: cannot mask %T into %T", src, dst)
+	}
+
+	s := m.newMaskState()
+	rv, err := m.mask(s, sv, "", reflect.Value{})
+	if err != nil {
+		return err
+	}
+	if !rv.Type().AssignableTo(dv.Type().Elem()) {
+		return fmt.Errorf("mask: masked value of type %s cannot be stored in %T", rv.Type(), dst)
+	}
+	dv.Elem().Set(rv)
+
+	return s.collectedError()
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// maskState holds the state of a single call to Mask.
+type maskState struct {
+	// visited maps the pointers and maps that have already been masked to their masked values,
+	// so that cyclic references are not followed forever and shared references stay shared.
+	visited map[visitKey]reflect.Value
+	// ancestors holds the pointers and maps being masked from the root to the current value, if visited is keyed by the path.
+	ancestors map[visitKey]reflect.Value
+	// keepMarshalers keeps the untagged values implementing json.Marshaler as they are,
+	// and marshaled maps the paths to them in the JSON encoding, see jsonPath, to their encodings and the masked ones.
+	keepMarshalers bool
+	marshaled      map[string][]marshaledJSON
+	// depth is the number of values being masked from the root to the current value.
+	depth int
+	// trackPath keeps track of the path to the current value for the mask callbacks and the field path rules.
+	trackPath bool
+	path      []pathSegment
+	// defaultMask is the mask applied to the untagged leaf values, set by SetDefaultStringMask.
+	defaultMask string
+	// keep is set while masking a value tagged with MaskTypeKeep, so that defaultMask is not applied to it.
+	keep bool
+	// typeFuncs is set if any function is registered by RegisterMaskTypeFunc.
+	typeFuncs bool
+	// rareCounts counts the values tagged with MaskTypeRare in a batch, and countingRare is set while counting them.
+	rareCounts   map[rareKey]int
+	countingRare bool
+	// collectErrors is set in ErrorModeCollect, and errs holds the errors of the values that failed to be masked.
+	collectErrors bool
+	errs          FieldErrors
+	// level selects the mask of the tags with levels, like mask:"filled@debug,zero@info". It is set by WithLevel.
+	level string
+}
+
+// levelTag returns the mask of a tag with levels, like mask:"filled@debug,zero@info,hash", selected by the level of the call.
+// The mask without a level, "hash" in the example, is used if no mask has the level, and the tag options, such as "slice", are kept.
+// If there is no mask without a level either, the last mask with a level is used, so that the value is not left unmasked.
+func (s *maskState) levelTag(tag string) string {
+	if strings.IndexByte(tag, '@') < 0 {
+		return tag
+	}
+
+	var selected, fallback, last string
+	found := false
+	var options []string
+	for _, elem := range strings.Split(tag, ",") {
+		if isTagOption(elem) {
+			options = append(options, elem)
+			continue
+		}
+		mask, level, ok := strings.Cut(elem, "@")
+		if !ok {
+			if fallback == "" {
+				fallback = elem
+			}
+			continue
+		}
+		if !found && level == s.level {
+			selected, found = mask, true
+		}
+		last = mask
+	}
+	if !found {
+		selected = fallback
+	}
+	if selected == "" {
+		// fail closed when no mask is selected for the level
+		selected = last
+	}
+	if selected == "" {
+		return ""
+	}
+
+	return strings.Join(append([]string{selected}, options...), ",")
+}
+
+// fastPath reports whether the leaf values can be masked directly without going through mask.
+func (s *maskState) fastPath() bool {
+	return !s.trackPath && s.defaultMask == "" && !s.typeFuncs && s.rareCounts == nil
+}
+
+// visitKey identifies a pointer or a map by its address and type, and the tag it is masked with.
+// The type is needed because a struct and its first field share the same address.
+type visitKey struct {
+	ptr  uintptr
+	typ  reflect.Type
+	tag  string
+	path string
+}
+
+// visit returns the masked value of a pointer or a map that has already been masked with tag, and the key to register it with.
+// If the result depends on the path, as with the field path rules and the mask callbacks, the path is part of the key,
+// so that a value shared by several paths is masked for each of them, and only a reference to an ancestor resolves to it.
+func (s *maskState) visit(rv reflect.Value, tag string) (visitKey, reflect.Value, bool) {
+	vk := visitKey{ptr: rv.Pointer(), typ: rv.Type(), tag: tag}
+	if s.ancestors != nil {
+		if v, ok := s.ancestors[vk]; ok {
+			return vk, v, true
+		}
+		vk.path = s.pathString()
+	}
+	v, ok := s.visited[vk]
+
+	return vk, v, ok
+}
+
+// enter registers the masked value of a pointer or a map before masking its contents, so that a cyclic reference resolves to it.
+// leave must be called when its contents are masked.
+func (s *maskState) enter(vk visitKey, v reflect.Value) {
+	s.visited[vk] = v
+	if s.ancestors != nil {
+		vk.path = ""
+		s.ancestors[vk] = v
+	}
+}
+
+func (s *maskState) leave(vk visitKey) {
+	if s.ancestors != nil {
+		vk.path = ""
+		delete(s.ancestors, vk)
+	}
+}
+
+func (m *Masker) newMaskState() *maskState {
+	var ancestors map[visitKey]reflect.Value
+	if len(m.maskCallbackMap) > 0 || len(m.maskFieldPathMap) > 0 {
+		ancestors = make(map[visitKey]reflect.Value)
+	}
+	return &maskState{
+		ancestors:   ancestors,
+		visited:     make(map[visitKey]reflect.Value),
+		trackPath:   len(m.maskCallbackMap) > 0 || len(m.maskFieldPathMap) > 0 || m.errorMode == ErrorModeCollect,
+		defaultMask: m.defaultMask,
+		typeFuncs:   len(m.typeFuncMap) > 0,
+
+		collectErrors: m.errorMode == ErrorModeCollect,
+	}
+}
+
+// collectError records err with the path to the current value in ErrorModeCollect, and reports whether masking can continue.
+func (s *maskState) collectError(err error) bool {
+	if !s.collectErrors {
+		return false
+	}
+	s.errs = append(s.errs, &FieldError{Path: s.pathString(), Err: err})
+
+	return true
+}
+
+// collectedError returns the errors recorded by collectError, or nil if there are none.
+func (s *maskState) collectedError() error {
+	if len(s.errs) == 0 {
+		return nil
+	}
+
+	return s.errs
+}
+
+// pathSegment is a struct field name, a map key, or a slice index in the path to a value.
+// A struct field also holds the struct type and the field index, to find its name in the JSON encoding.
+type pathSegment struct {
+	name    string
+	parent  reflect.Type
+	field   int
+	key     reflect.Value
+	index   int
+	isIndex bool
+}
+
+func (s *maskState) push(seg pathSegment) {
+	if s.trackPath {
+		s.path = append(s.path, seg)
+	}
+}
+
+func (s *maskState) pop() {
+	if s.trackPath {
+		s.path = s.path[:len(s.path)-1]
+	}
+}
+
+// pathString returns the path to the current value, like "Users[0].Name" or "Meta.key".
+func (s *maskState) pathString() string {
+	var sb strings.Builder
+	for i, seg := range s.path {
+		if seg.isIndex {
+			sb.WriteString("[" + strconv.Itoa(seg.index) + "]")
+			continue
+		}
+		if i > 0 {
+			sb.WriteByte('.')
+		}
+		if seg.key.IsValid() {
+			sb.WriteString(fmt.Sprint(seg.key.Interface()))
+		} else {
+			sb.WriteString(seg.name)
 		}
 	}
-	if len(digits) == 2 {
-		if d, err = strconv.Atoi(digits[1]); err != nil {
-			return 0, err
+
+	return sb.String()
+}
+
+// fieldPath returns the path to the child named name of the current value in the form used by RegisterMaskFieldPath,
+// with the struct field names and map keys joined by dots and the slice indexes omitted, like "Users.Name".
+func (s *maskState) fieldPath(name string) string {
+	var sb strings.Builder
+	for _, seg := range s.path {
+		if seg.isIndex {
+			continue
+		}
+		if seg.key.IsValid() {
+			sb.WriteString(fmt.Sprint(seg.key.Interface()))
+		} else {
+			sb.WriteString(seg.name)
 		}
+		sb.WriteByte('.')
 	}
+	sb.WriteString(name)
 
-	dd := math.Pow10(d)
-	x := float64(int(rand.Float64() * float64(i) * dd))
+	return sb.String()
+}
 
-	return x / dd, nil
+// getPathTag returns the mask tag registered with RegisterMaskFieldPath for the child named name of the current value,
+// if the child has no tag of its own.
+func (m *Masker) getPathTag(s *maskState, tag, name string) string {
+	if tag == "" && len(m.maskFieldPathMap) > 0 {
+		tag = m.maskFieldPathMap[s.fieldPath(name)]
+	}
+	return tag
 }
 
-// MaskZero converts the value to its type's zero value.
-func (m *Masker) MaskZero(arg string, value any) (any, error) {
-	return reflect.Zero(reflect.TypeOf(value)).Interface(), nil
+// maskChild masks a field, an element, or a map value of the current value.
+func (m *Masker) maskChild(s *maskState, seg pathSegment, rv reflect.Value, tag string, mp reflect.Value) (reflect.Value, error) {
+	s.push(seg)
+	v, err := m.mask(s, rv, tag, mp)
+	if err != nil && s.collectError(err) {
+		v, err = reflect.Zero(rv.Type()), nil
+	}
+	s.pop()
+
+	return v, err
 }
 
-// Mask returns an object with the mask applied to any given object.
-// The function's argument can accept any type, including pointer, map, and slice types, in addition to struct.
-func (m *Masker) Mask(target any) (ret any, err error) {
-	rv, err := m.mask(reflect.ValueOf(target), "", reflect.Value{})
-	if err != nil {
-		return ret, err
+func (m *Masker) mask(s *maskState, rv reflect.Value, tag string, mp reflect.Value) (reflect.Value, error) {
+	if m.maxDepth > 0 && s.depth >= m.maxDepth {
+		return reflect.Value{}, fmt.Errorf("mask: max depth %d exceeded", m.maxDepth)
 	}
+	s.depth++
+	v, err := m.maskValue(s, rv, tag, mp)
+	s.depth--
 
-	return rv.Interface(), nil
+	return v, err
 }
 
-func (m *Masker) mask(rv reflect.Value, tag string, mp reflect.Value) (reflect.Value, error) {
+func (m *Masker) maskValue(s *maskState, rv reflect.Value, tag string, mp reflect.Value) (reflect.Value, error) {
+	tag = s.levelTag(tag)
+	if s.defaultMask != "" {
+		if trimTagOptions(tag) == MaskTypeKeep {
+			return m.maskKept(s, rv, mp)
+		}
+		if tag == "" && !s.keep && isLeafKind(rv.Kind()) {
+			tag = s.levelTag(s.defaultMask)
+		}
+	}
+	if fn, ok := m.typeFuncMap[rv.Type()]; ok {
+		return m.maskTypeFunc(rv, fn)
+	}
+	if unwrap, ok := m.unwrapperMap[rv.Type()]; ok {
+		return m.maskWrapper(s, rv, tag, unwrap)
+	}
+	if s.keepMarshalers && tag == "" && isJSONMarshaler(rv.Type()) {
+		// masked in the marshalled form, unless a tag or a handler applies to it
+		return m.keepMarshaler(s, rv)
+	}
+	kind := rv.Type().Kind()
+	if _, _, ok := cutTagOption(tag, TagOptionSlice); ok && (kind == reflect.Slice || kind == reflect.Array) {
+		// the mask is applied to each element in the range instead of the whole slice
+		if kind == reflect.Slice && rv.IsNil() {
+			return reflect.Zero(rv.Type()), nil
+		}
+		return m.maskSlice(s, rv, tag, mp)
+	}
+	if name, ok := cutCallbackTag(tag); ok {
+		return m.maskCallback(s, rv, name)
+	}
+	if s.rareCounts != nil && trimTagOptions(tag) == MaskTypeRare {
+		return m.maskRare(s, rv, mp)
+	}
 	if ok, v, err := m.maskAnyValue(tag, rv); ok {
 		return v, err
 	}
-	switch rv.Type().Kind() {
+	if rv.Type() == timeType {
+		// time.Time only has unexported fields, so it is copied as a whole
+		if mp.IsValid() {
+			mp.Set(rv)
+			return mp, nil
+		}
+		return rv, nil
+	}
+	if kind != reflect.Interface && kind != reflect.Ptr && isMaskValuer(rv.Type()) && !m.hasKindFunc(kind, tag) {
+		return m.maskValuer(rv)
+	}
+	switch kind {
 	case reflect.Interface:
-		return m.maskInterface(rv, tag, mp)
+		return m.maskInterface(s, rv, tag, mp)
 	case reflect.Ptr:
-		return m.maskPtr(rv, tag, mp)
+		return m.maskPtr(s, rv, tag, mp)
 	case reflect.Struct:
-		return m.maskStruct(rv, tag, mp)
+		return m.maskStruct(s, rv, tag, mp)
 	case reflect.Array:
-		return m.maskSlice(rv, tag, mp)
+		return m.maskSlice(s, rv, tag, mp)
 	case reflect.Slice:
 		if rv.IsNil() {
 			return reflect.Zero(rv.Type()), nil
 		}
-		return m.maskSlice(rv, tag, mp)
+		return m.maskSlice(s, rv, tag, mp)
 	case reflect.Map:
-		return m.maskMap(rv, tag, mp)
+		return m.maskMap(s, rv, tag, mp)
 	case reflect.String:
 		return m.maskString(rv, tag, mp)
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
@@ -479,13 +3253,157 @@ func (m *Masker) mask(rv reflect.Value, tag string, mp reflect.Value) (reflect.V
 	}
 }
 
-func (m *Masker) maskInterface(rv reflect.Value, tag string, _ reflect.Value) (reflect.Value, error) {
+// isMaskValuer reports whether the value or a pointer to it implements MaskValuer.
+func isMaskValuer(rt reflect.Type) bool {
+	return rt.Implements(maskValuerType) || reflect.PtrTo(rt).Implements(maskValuerType)
+}
+
+// hasKindFunc reports whether a mask func registered for the kind, such as a string func for a string, matches the tag.
+func (m *Masker) hasKindFunc(kind reflect.Kind, tag string) bool {
+	if tag == "" {
+		return false
+	}
+	var keys []string
+	switch kind {
+	case reflect.String:
+		keys = m.maskStringFuncKeys
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		keys = m.maskIntFuncKeys
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		keys = m.maskUintFuncKeys
+	case reflect.Float32, reflect.Float64:
+		keys = m.maskFloat64FuncKeys
+	}
+	tag = trimTagOptions(tag)
+	for _, mt := range keys {
+		if strings.HasPrefix(tag, mt) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Masker) maskValuer(rv reflect.Value) (reflect.Value, error) {
+	var valuer MaskValuer
+	if rv.Type().Implements(maskValuerType) {
+		valuer = rv.Interface().(MaskValuer)
+	} else {
+		if !rv.CanAddr() {
+			// the method has a pointer receiver, so call it on a copy
+			rv2 := reflect.New(rv.Type()).Elem()
+			rv2.Set(rv)
+			rv = rv2
+		}
+		valuer = rv.Addr().Interface().(MaskValuer)
+	}
+
+	v, err := valuer.MaskValue()
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	if v == nil {
+		return reflect.Zero(rv.Type()), nil
+	}
+	masked := reflect.ValueOf(v)
+	if !masked.Type().AssignableTo(rv.Type()) {
+		return reflect.Value{}, fmt.Errorf("mask: MaskValue returned %T for a value of type %s", v, rv.Type())
+	}
+
+	return masked, nil
+}
+
+// maskKept masks a value tagged with MaskTypeKeep without applying the default mask to it and its descendants.
+func (m *Masker) maskKept(s *maskState, rv reflect.Value, mp reflect.Value) (reflect.Value, error) {
+	keep := s.keep
+	s.keep = true
+	v, err := m.maskValue(s, rv, "", mp)
+	s.keep = keep
+
+	return v, err
+}
+
+// isLeafKind reports whether the kind is of a string or a number, to which the default mask is applied.
+func isLeafKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// cutCallbackTag returns the name of the callback if the tag is like "cb:name".
+func cutCallbackTag(tag string) (string, bool) {
+	tag = trimTagOptions(tag)
+	if !strings.HasPrefix(tag, tagPrefixCallback) {
+		return "", false
+	}
+	return tag[len(tagPrefixCallback):], true
+}
+
+func (m *Masker) maskCallback(s *maskState, rv reflect.Value, name string) (reflect.Value, error) {
+	fn, ok := m.maskCallbackMap[name]
+	if !ok {
+		return reflect.Value{}, fmt.Errorf("mask: callback %q is not registered", name)
+	}
+
+	v, err := fn(s.pathString(), rv.Interface())
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	if v == nil {
+		return reflect.Zero(rv.Type()), nil
+	}
+	masked := reflect.ValueOf(v)
+	if !masked.Type().AssignableTo(rv.Type()) {
+		return reflect.Value{}, fmt.Errorf("mask: callback %q returned %T for a value of type %s", name, v, rv.Type())
+	}
+
+	return masked, nil
+}
+
+func (m *Masker) maskTypeFunc(rv reflect.Value, fn func(value any) (any, error)) (reflect.Value, error) {
+	v, err := fn(rv.Interface())
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	if v == nil {
+		return reflect.Zero(rv.Type()), nil
+	}
+	masked := reflect.ValueOf(v)
+	if !masked.Type().AssignableTo(rv.Type()) {
+		return reflect.Value{}, fmt.Errorf("mask: type func returned %T for a value of type %s", v, rv.Type())
+	}
+
+	return masked, nil
+}
+
+func (m *Masker) maskWrapper(s *maskState, rv reflect.Value, tag string, unwrap UnwrapFunc) (reflect.Value, error) {
+	payload, rewrap := unwrap(rv.Interface())
+	if payload == nil {
+		return reflect.ValueOf(rewrap(nil)), nil
+	}
+
+	masked, err := m.mask(s, reflect.ValueOf(payload), tag, reflect.Value{})
+	if err != nil {
+		return reflect.Value{}, err
+	}
+
+	return reflect.ValueOf(rewrap(masked.Interface())), nil
+}
+
+func (m *Masker) maskInterface(s *maskState, rv reflect.Value, tag string, _ reflect.Value) (reflect.Value, error) {
 	if rv.IsNil() {
 		return reflect.Zero(rv.Type()), nil
 	}
+	if rv.Type() == errorType && tag != "" {
+		return m.maskError(rv, tag)
+	}
 
 	mp := reflect.New(rv.Type()).Elem()
-	rv2, err := m.mask(reflect.ValueOf(rv.Interface()), tag, reflect.Value{})
+	rv2, err := m.mask(s, reflect.ValueOf(rv.Interface()), tag, reflect.Value{})
 	if err != nil {
 		return reflect.Value{}, err
 	}
@@ -494,13 +3412,34 @@ func (m *Masker) maskInterface(rv reflect.Value, tag string, _ reflect.Value) (r
 	return mp, nil
 }
 
-func (m *Masker) maskPtr(rv reflect.Value, tag string, _ reflect.Value) (reflect.Value, error) {
+// maskError masks the message of an error with the tag, and returns a new error with the masked message.
+// The masked error does not wrap the original one, so that the original message cannot be retrieved from it.
+func (m *Masker) maskError(rv reflect.Value, tag string) (reflect.Value, error) {
+	msg, err := m.String(tag, rv.Interface().(error).Error())
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	mp := reflect.New(errorType).Elem()
+	mp.Set(reflect.ValueOf(errors.New(msg)))
+
+	return mp, nil
+}
+
+func (m *Masker) maskPtr(s *maskState, rv reflect.Value, tag string, _ reflect.Value) (reflect.Value, error) {
 	if rv.IsNil() {
 		return reflect.Zero(rv.Type()), nil
 	}
 
+	vk, v, ok := s.visit(rv, tag)
+	if ok {
+		return v, nil
+	}
+
 	mp := reflect.New(rv.Type().Elem())
-	rv2, err := m.mask(rv.Elem(), tag, mp.Elem())
+	// register the new pointer before masking the pointee so that a cyclic reference resolves to it
+	s.enter(vk, mp)
+	defer s.leave(vk)
+	rv2, err := m.mask(s, rv.Elem(), tag, mp.Elem())
 	if err != nil {
 		return reflect.Value{}, err
 	}
@@ -509,7 +3448,7 @@ func (m *Masker) maskPtr(rv reflect.Value, tag string, _ reflect.Value) (reflect
 	return mp, nil
 }
 
-func (m *Masker) maskStruct(rv reflect.Value, tag string, mp reflect.Value) (reflect.Value, error) {
+func (m *Masker) maskStruct(s *maskState, rv reflect.Value, tag string, mp reflect.Value) (reflect.Value, error) {
 	if rv.IsZero() {
 		return reflect.Zero(rv.Type()), nil
 	}
@@ -523,21 +3462,48 @@ func (m *Masker) maskStruct(rv reflect.Value, tag string, mp reflect.Value) (ref
 		m.mu.RUnlock()
 		if !ok {
 			m.mu.Lock()
-			st.value = reflect.New(rt).Elem()
 			for i := 0; i < rt.NumField(); i++ {
 				st.structFields = append(st.structFields, rt.Field(i))
+				st.plainFields = append(st.plainFields, isPlainType(rt.Field(i).Type))
 			}
+			st.defaultTag = m.structDefaultTag(rt)
+			st.clone = structCloneMethod(rt)
 			m.typeToStructCache[rt] = st
 			m.mu.Unlock()
 		}
-		if !mp.IsValid() {
-			mp = st.value
-		}
 	} else {
-		if !mp.IsValid() {
-			mp = reflect.New(rt).Elem()
+		st.defaultTag = m.structDefaultTag(rt)
+		st.clone = structCloneMethod(rt)
+	}
+	if !mp.IsValid() {
+		// a new value for each call, as the masked value must not be shared between concurrent calls
+		mp = reflect.New(rt).Elem()
+	}
+	cloned := false
+	if m.useClone {
+		if c, ok := st.clone.call(rv); ok {
+			// the unexported fields are copied by Clone, and the exported fields are masked below
+			mp.Set(c)
+			cloned = s.fastPath() && !m.unwrapsPlainTypes()
+		}
+	}
+	if m.maskUnexported && !rv.CanAddr() {
+		// unexported fields can only be read through an addressable value
+		rv2 := reflect.New(rt).Elem()
+		rv2.Set(rv)
+		rv = rv2
+	}
+	inherited := ""
+	if m.inheritTag {
+		inherited = tag
+		if st.defaultTag != "" {
+			inherited = st.defaultTag
 		}
 	}
+	var groupTags map[string]string
+	if len(m.fieldGroups) > 0 {
+		groupTags = m.fieldGroupTags(s, rt)
+	}
 
 	for i := 0; i < rt.NumField(); i++ {
 		var field reflect.StructField
@@ -546,41 +3512,196 @@ func (m *Masker) maskStruct(rv reflect.Value, tag string, mp reflect.Value) (ref
 		} else {
 			field = rt.Field(i)
 		}
+		tag := field.Tag.Get(m.tagName)
 		// skip private field
 		if field.PkgPath != "" {
-			continue
-		}
-		tag := field.Tag.Get(m.tagName)
-		switch field.Type.Kind() {
-		case reflect.String:
-			s, err := m.String(m.getTag(tag, field.Name), rv.Field(i).String())
+			if !m.maskUnexported || field.Name == "_" {
+				continue
+			}
+			if tag = m.getFieldTag(rt, m.getPathTag(s, tag, field.Name), field); tag == "" {
+				tag = m.resolvePolicy(groupTags[field.Name])
+			}
+			if tag == "" {
+				tag = m.resolvePolicy(inherited)
+			}
+			s.push(pathSegment{name: field.Name, parent: rt, field: i})
+			err := m.maskUnexportedField(s, rv.Field(i), tag, mp.Field(i))
+			s.pop()
 			if err != nil {
 				return reflect.Value{}, err
 			}
-			mp.Field(i).SetString(s)
-		default:
-			rvf, err := m.mask(rv.Field(i), m.getTag(tag, field.Name), mp.Field(i))
+			continue
+		}
+		tag = m.getFieldTag(rt, m.getPathTag(s, tag, field.Name), field)
+		if tag == "" {
+			tag = m.resolvePolicy(groupTags[field.Name])
+		}
+		if tag == "" && inherited != "" {
+			tag = m.resolvePolicy(inherited)
+		} else if field.Type.Kind() == reflect.String && tag == "" {
+			tag = m.resolvePolicy(st.defaultTag)
+		}
+		tag = s.levelTag(tag)
+		if cloned && tag == "" && (m.cache && st.plainFields[i] || !m.cache && isPlainType(field.Type)) {
+			// already copied by Clone
+			continue
+		}
+		if field.Type.Kind() == reflect.String && s.fastPath() && !isMaskValuer(field.Type) {
+			masked, err := m.String(tag, rv.Field(i).String())
 			if err != nil {
 				return reflect.Value{}, err
 			}
-			mp.Field(i).Set(rvf)
+			mp.Field(i).SetString(masked)
+			continue
+		}
+		rvf, err := m.maskChild(s, pathSegment{name: field.Name, parent: rt, field: i}, rv.Field(i), tag, mp.Field(i))
+		if err != nil {
+			return reflect.Value{}, err
 		}
+		mp.Field(i).Set(rvf)
 	}
 
 	return mp, nil
 }
 
-func (m *Masker) maskSlice(rv reflect.Value, tag string, mp reflect.Value) (reflect.Value, error) {
-	var rv2 reflect.Value
+func (m *Masker) maskUnexportedField(s *maskState, rv reflect.Value, tag string, mp reflect.Value) error {
+	src := reflect.NewAt(rv.Type(), unsafe.Pointer(rv.UnsafeAddr())).Elem()
+	dst := reflect.NewAt(mp.Type(), unsafe.Pointer(mp.UnsafeAddr())).Elem()
+	rvf, err := m.mask(s, src, tag, dst)
+	if err != nil {
+		if !s.collectError(err) {
+			return err
+		}
+		rvf = reflect.Zero(dst.Type())
+	}
+	dst.Set(rvf)
+
+	return nil
+}
+
+// isPlainType reports whether the values of the type have nothing to mask without a tag,
+// as they hold no structs, interfaces, maps, or values implementing MaskValuer.
+func isPlainType(rt reflect.Type) bool {
+	if isMaskValuer(rt) {
+		return false
+	}
+	switch rt.Kind() {
+	case reflect.Bool, reflect.Complex64, reflect.Complex128:
+		return true
+	case reflect.Slice, reflect.Array, reflect.Ptr:
+		return isPlainType(rt.Elem())
+	}
+	return isLeafKind(rt.Kind())
+}
+
+// unwrapsPlainTypes reports whether an UnwrapFunc is registered for a type that isPlainType reports as having nothing to mask.
+func (m *Masker) unwrapsPlainTypes() bool {
+	for rt := range m.unwrapperMap {
+		if isPlainType(rt) {
+			return true
+		}
+	}
+	return false
+}
+
+// cloneMethod is the Clone method of a struct type, which returns a copy of the struct as the type or a pointer to it.
+type cloneMethod struct {
+	fn      reflect.Value
+	ptrRecv bool
+	ptrOut  bool
+}
+
+// structCloneMethod returns the Clone method of the struct of type rt, with a value or a pointer receiver.
+// A method with another signature is ignored.
+func structCloneMethod(rt reflect.Type) cloneMethod {
+	ptrRecv := false
+	method, ok := rt.MethodByName("Clone")
+	if !ok {
+		if method, ok = reflect.PtrTo(rt).MethodByName("Clone"); !ok {
+			return cloneMethod{}
+		}
+		ptrRecv = true
+	}
+	// the receiver is the first argument
+	ft := method.Type
+	if ft.NumIn() != 1 || ft.NumOut() != 1 {
+		return cloneMethod{}
+	}
+	switch ft.Out(0) {
+	case rt:
+		return cloneMethod{fn: method.Func, ptrRecv: ptrRecv}
+	case reflect.PtrTo(rt):
+		return cloneMethod{fn: method.Func, ptrRecv: ptrRecv, ptrOut: true}
+	}
+
+	return cloneMethod{}
+}
+
+// call returns the copy of the struct made by the Clone method, or false if the struct has no Clone method.
+func (c cloneMethod) call(rv reflect.Value) (reflect.Value, bool) {
+	if !c.fn.IsValid() || !rv.CanInterface() {
+		return reflect.Value{}, false
+	}
+	if c.ptrRecv {
+		if !rv.CanAddr() {
+			// the method has a pointer receiver, so call it on a copy
+			rv2 := reflect.New(rv.Type()).Elem()
+			rv2.Set(rv)
+			rv = rv2
+		}
+		rv = rv.Addr()
+	}
+	out := c.fn.Call([]reflect.Value{rv})[0]
+	if c.ptrOut {
+		if out.IsNil() {
+			return reflect.Value{}, false
+		}
+		out = out.Elem()
+	}
+
+	return out, true
+}
+
+// structDefaultTag returns the mask tag set by the "default:" directive on a blank field of the struct.
+// For example, a field `_ struct{}` tagged with mask:"default:hash" masks all untagged string fields of the struct with "hash".
+func (m *Masker) structDefaultTag(rt reflect.Type) string {
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if field.Name != "_" {
+			continue
+		}
+		if tag := field.Tag.Get(m.tagName); strings.HasPrefix(tag, structDirectiveDefault) {
+			return tag[len(structDirectiveDefault):]
+		}
+	}
+
+	return ""
+}
+
+func (m *Masker) maskSlice(s *maskState, rv reflect.Value, tag string, mp reflect.Value) (reflect.Value, error) {
+	tag, start, end, err := sliceRange(tag, rv.Len())
+	if err != nil {
+		return reflect.Value{}, err
+	}
 
+	var rv2 reflect.Value
 	if rt := rv.Type(); rt.Kind() == reflect.Array {
 		rv2 = reflect.New(rt).Elem()
 	} else {
 		rv2 = reflect.MakeSlice(rv.Type(), rv.Len(), rv.Len())
 	}
+	elemKind := rv.Type().Elem().Kind()
+	if !s.fastPath() || isMaskValuer(rv.Type().Elem()) {
+		// mask the elements through mask to keep track of the path, apply the default mask, or call MaskValue
+		elemKind = reflect.Invalid
+	}
 	for i := 0; i < rv.Len(); i++ {
 		value := rv.Index(i)
-		switch rv.Type().Elem().Kind() {
+		tag := tag
+		if i < start || i >= end {
+			tag = ""
+		}
+		switch elemKind {
 		case reflect.String:
 			rvf, err := m.String(tag, value.String())
 			if err != nil {
@@ -606,7 +3727,7 @@ func (m *Masker) maskSlice(rv reflect.Value, tag string, mp reflect.Value) (refl
 			}
 			rv2.Index(i).SetUint(uint64(rvf))
 		default:
-			rvf, err := m.mask(value, tag, rv2.Index(i))
+			rvf, err := m.maskChild(s, pathSegment{index: i, isIndex: true}, value, tag, rv2.Index(i))
 			if err != nil {
 				return reflect.Value{}, err
 			}
@@ -622,14 +3743,39 @@ func (m *Masker) maskSlice(rv reflect.Value, tag string, mp reflect.Value) (refl
 	return rv2, nil
 }
 
-func (m *Masker) maskMap(rv reflect.Value, tag string, mp reflect.Value) (reflect.Value, error) {
+func (m *Masker) maskMap(s *maskState, rv reflect.Value, tag string, mp reflect.Value) (reflect.Value, error) {
 	if rv.IsNil() {
 		return reflect.Zero(rv.Type()), nil
 	}
 
+	if tag, keyTag, ok := cutTagOption(tag, TagOptionKeys); ok {
+		// without an argument, the keys are masked in the same way as the values
+		if keyTag == "" {
+			keyTag = trimTagOptions(tag)
+		}
+		var rv2 reflect.Value
+		var err error
+		switch rv.Type().Key().Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			rv2, err = m.maskIntKeyMap(s, rv, tag, keyTag)
+		case reflect.String:
+			rv2, err = m.maskStringKeysMap(s, rv, tag, keyTag)
+		default:
+			return reflect.Value{}, fmt.Errorf("mask: %s option is not supported for map keys of type %s", TagOptionKeys, rv.Type().Key())
+		}
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		if mp.IsValid() {
+			mp.Set(rv2)
+			return mp, nil
+		}
+		return rv2, nil
+	}
+
 	switch rv.Type().Key().Kind() {
 	case reflect.String:
-		rv2, err := m.maskStringKeyMap(rv, tag)
+		rv2, err := m.maskStringKeyMap(s, rv, tag)
 		if err != nil {
 			return reflect.Value{}, err
 		}
@@ -641,7 +3787,7 @@ func (m *Masker) maskMap(rv reflect.Value, tag string, mp reflect.Value) (reflec
 		}
 	}
 
-	rv2, err := m.maskAnyKeyMap(rv, tag)
+	rv2, err := m.maskAnyKeyMap(s, rv, tag)
 	if err != nil {
 		return reflect.Value{}, err
 	}
@@ -653,12 +3799,92 @@ func (m *Masker) maskMap(rv reflect.Value, tag string, mp reflect.Value) (reflec
 	return rv2, nil
 }
 
-func (m *Masker) maskAnyKeyMap(rv reflect.Value, tag string) (reflect.Value, error) {
+// maskIntKeyMap masks the int keys of a map with keyTag, and the values with tag.
+// The keys are masked in ascending order, and if a masked key collides with an earlier one, the entry is dropped.
+func (m *Masker) maskIntKeyMap(s *maskState, rv reflect.Value, tag, keyTag string) (reflect.Value, error) {
+	vk, v, ok := s.visit(rv, tag+","+TagOptionKeys+":"+keyTag)
+	if ok {
+		return v, nil
+	}
+	rv2 := reflect.MakeMapWithSize(rv.Type(), rv.Len())
+	s.enter(vk, rv2)
+	defer s.leave(vk)
+
+	keys := rv.MapKeys()
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Int() < keys[j].Int() })
+	for _, key := range keys {
+		k, err := m.Int(keyTag, int(key.Int()))
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		if k, err = fitInt(keyTag, k, rv.Type().Key().Bits()); err != nil {
+			return reflect.Value{}, err
+		}
+		newKey := reflect.ValueOf(k).Convert(rv.Type().Key())
+		if rv2.MapIndex(newKey).IsValid() {
+			continue
+		}
+		rf, err := m.maskChild(s, pathSegment{key: key}, rv.MapIndex(key), tag, reflect.Value{})
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		rv2.SetMapIndex(newKey, rf)
+	}
+
+	return rv2, nil
+}
+
+// maskStringKeysMap masks the string keys of a map with keyTag, and the values with tag.
+// The keys are masked in ascending order, and if a masked key collides with an earlier one, the entry is dropped.
+func (m *Masker) maskStringKeysMap(s *maskState, rv reflect.Value, tag, keyTag string) (reflect.Value, error) {
+	vk, v, ok := s.visit(rv, tag+","+TagOptionKeys+":"+keyTag)
+	if ok {
+		return v, nil
+	}
+	rv2 := reflect.MakeMapWithSize(rv.Type(), rv.Len())
+	s.enter(vk, rv2)
+	defer s.leave(vk)
+
+	keys := rv.MapKeys()
+	sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+	for _, key := range keys {
+		k, err := m.String(keyTag, key.String())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		newKey := reflect.ValueOf(k).Convert(rv.Type().Key())
+		if rv2.MapIndex(newKey).IsValid() {
+			continue
+		}
+		rf, err := m.maskChild(s, pathSegment{key: key}, rv.MapIndex(key), tag, reflect.Value{})
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		rv2.SetMapIndex(newKey, rf)
+	}
+
+	return rv2, nil
+}
+
+func (m *Masker) maskAnyKeyMap(s *maskState, rv reflect.Value, tag string) (reflect.Value, error) {
+	vk, v, ok := s.visit(rv, tag)
+	if ok {
+		return v, nil
+	}
 	rv2 := reflect.MakeMapWithSize(rv.Type(), rv.Len())
+	s.enter(vk, rv2)
+	defer s.leave(vk)
+	// interface keys, such as those of map[any]any decoded from YAML, are matched with the field rules by their string form
+	interfaceKey := rv.Type().Key().Kind() == reflect.Interface && tag == "" && (len(m.maskFieldMap) > 0 || len(m.maskFieldPathMap) > 0)
 	iter := rv.MapRange()
 	for iter.Next() {
 		key, value := iter.Key(), iter.Value()
-		rf, err := m.mask(value, tag, reflect.Value{})
+		valueTag := tag
+		if interfaceKey && !key.IsNil() {
+			name := fmt.Sprint(key.Interface())
+			valueTag = m.getTag(m.getPathTag(s, tag, name), name)
+		}
+		rf, err := m.maskChild(s, pathSegment{key: key}, value, valueTag, reflect.Value{})
 		if err != nil {
 			return reflect.Value{}, err
 		}
@@ -668,12 +3894,17 @@ func (m *Masker) maskAnyKeyMap(rv reflect.Value, tag string) (reflect.Value, err
 	return rv2, nil
 }
 
-func (m *Masker) maskStringKeyMap(rv reflect.Value, tag string) (reflect.Value, error) {
-	switch rv.Type().Elem().Kind() {
+func (m *Masker) maskStringKeyMap(s *maskState, rv reflect.Value, tag string) (reflect.Value, error) {
+	elemKind := rv.Type().Elem().Kind()
+	if !s.fastPath() || rv.Type().PkgPath() != "" || rv.Type().Key().PkgPath() != "" || rv.Type().Elem().PkgPath() != "" {
+		// mask the values through mask to keep track of the path, apply the default mask, or keep the named types
+		elemKind = reflect.Invalid
+	}
+	switch elemKind {
 	case reflect.String:
 		mm := make(map[string]string, rv.Len())
 		for k, v := range rv.Interface().(map[string]string) {
-			rvf, err := m.String(m.getTag(tag, k), v)
+			rvf, err := m.String(s.levelTag(m.getTag(tag, k)), v)
 			if err != nil {
 				return reflect.Value{}, err
 			}
@@ -684,7 +3915,7 @@ func (m *Masker) maskStringKeyMap(rv reflect.Value, tag string) (reflect.Value,
 	case reflect.Int:
 		mm := make(map[string]int, rv.Len())
 		for k, v := range rv.Interface().(map[string]int) {
-			rvf, err := m.Int(m.getTag(tag, k), v)
+			rvf, err := m.Int(s.levelTag(m.getTag(tag, k)), v)
 			if err != nil {
 				return reflect.Value{}, err
 			}
@@ -694,7 +3925,7 @@ func (m *Masker) maskStringKeyMap(rv reflect.Value, tag string) (reflect.Value,
 	case reflect.Float64:
 		mm := make(map[string]float64, rv.Len())
 		for k, v := range rv.Interface().(map[string]float64) {
-			rvf, err := m.Float64(m.getTag(tag, k), v)
+			rvf, err := m.Float64(s.levelTag(m.getTag(tag, k)), v)
 			if err != nil {
 				return reflect.Value{}, err
 			}
@@ -703,11 +3934,17 @@ func (m *Masker) maskStringKeyMap(rv reflect.Value, tag string) (reflect.Value,
 
 		return reflect.ValueOf(mm), nil
 	default:
+		vk, v, ok := s.visit(rv, tag)
+		if ok {
+			return v, nil
+		}
 		rv2 := reflect.MakeMapWithSize(rv.Type(), rv.Len())
+		s.enter(vk, rv2)
+		defer s.leave(vk)
 		iter := rv.MapRange()
 		for iter.Next() {
 			key, value := iter.Key(), iter.Value()
-			rf, err := m.mask(value, m.getTag(tag, key.String()), reflect.Value{})
+			rf, err := m.maskChild(s, pathSegment{key: key}, value, m.getTag(m.getPathTag(s, tag, key.String()), key.String()), reflect.Value{})
 			if err != nil {
 				return reflect.Value{}, err
 			}
@@ -736,6 +3973,10 @@ func (m *Masker) maskString(rv reflect.Value, tag string, mp reflect.Value) (ref
 		return mp, nil
 	}
 
+	if rv.Type().PkgPath() != "" {
+		return valueOfString(sp).Convert(rv.Type()), nil
+	}
+
 	return valueOfString(sp), nil
 }
 
@@ -756,6 +3997,9 @@ func (m *Masker) maskInt(rv reflect.Value, tag string, mp reflect.Value) (reflec
 	if err != nil {
 		return reflect.Value{}, err
 	}
+	if ip, err = fitInt(tag, ip, rv.Type().Bits()); err != nil {
+		return reflect.Value{}, err
+	}
 	if mp.IsValid() {
 		mp.SetInt(int64(ip))
 		return mp, nil
@@ -781,6 +4025,7 @@ func (m *Masker) maskUint(rv reflect.Value, tag string, mp reflect.Value) (refle
 	if err != nil {
 		return reflect.Value{}, err
 	}
+	ip = saturateUint(ip, rv.Type().Bits())
 	if mp.IsValid() {
 		mp.SetUint(uint64(ip))
 		return mp, nil
@@ -793,6 +4038,46 @@ func (m *Masker) maskUint(rv reflect.Value, tag string, mp reflect.Value) (refle
 	return reflect.ValueOf(&ip).Elem(), nil
 }
 
+// fitInt limits a value masked with tag to the range of an int type of the size bits.
+// A value encrypted by "fpe" is not saturated, since it could not be decrypted, and an error is returned instead.
+func fitInt(tag string, v, bits int) (int, error) {
+	if s := saturateInt(v, bits); s != v {
+		if trimTagOptions(tag) == MaskTypeFPE {
+			return 0, fmt.Errorf("mask: FPE result %d overflows int%d", v, bits)
+		}
+		return s, nil
+	}
+	return v, nil
+}
+
+// saturateInt limits a masked value to the range of an int type of the size bits,
+// so that a value out of the range, such as 999 given by "random1000" for an int8, is not wrapped around.
+func saturateInt(v, bits int) int {
+	if bits >= strconv.IntSize {
+		return v
+	}
+	max := 1<<(bits-1) - 1
+	min := -1 << (bits - 1)
+	if v > max {
+		return max
+	}
+	if v < min {
+		return min
+	}
+	return v
+}
+
+// saturateUint limits a masked value to the range of a uint type of the size bits.
+func saturateUint(v uint, bits int) uint {
+	if bits >= strconv.IntSize {
+		return v
+	}
+	if max := uint(1)<<bits - 1; v > max {
+		return max
+	}
+	return v
+}
+
 func (m *Masker) maskfloat(rv reflect.Value, tag string, mp reflect.Value) (reflect.Value, error) {
 	if tag == "" {
 		if mp.IsValid() {
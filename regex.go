@@ -0,0 +1,70 @@
+package mask
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// RegisterMaskRegex registers a regular expression with named groups that can be referred to with a tag like mask:"namedgroups:name:group=mask"
+// from default masker.
+func RegisterMaskRegex(name string, re *regexp.Regexp) {
+	defaultMasker.RegisterMaskRegex(name, re)
+}
+
+// RegisterMaskRegex registers a regular expression with named groups that can be referred to with a tag like mask:"namedgroups:name:group=mask".
+func (m *Masker) RegisterMaskRegex(name string, re *regexp.Regexp) {
+	m.regexMap[name] = re
+}
+
+// MaskNamedGroupsString masks the named groups of the matches of a regular expression registered by RegisterMaskRegex.
+// The name of the regular expression and the action for each group are passed to arg like ":phone:area=keep,number=mask",
+// and the characters of the groups with "mask" are masked while those of the groups with "keep", the other groups,
+// and the rest of the string are kept. If the string does not match, it is returned as is.
+func (m *Masker) MaskNamedGroupsString(arg, value string) (string, error) {
+	name, rules, _ := strings.Cut(strings.TrimPrefix(arg, ":"), ":")
+	re, ok := m.regexMap[name]
+	if !ok {
+		return "", fmt.Errorf("mask: regex %q is not registered", name)
+	}
+
+	var groups []int
+	for _, rule := range strings.Split(rules, ",") {
+		if rule == "" {
+			continue
+		}
+		group, action, _ := strings.Cut(rule, "=")
+		i := re.SubexpIndex(group)
+		if i < 0 {
+			return "", fmt.Errorf("mask: regex %q has no group %q", name, group)
+		}
+		switch action {
+		case "mask":
+			groups = append(groups, i)
+		case "keep":
+		default:
+			return "", fmt.Errorf("mask: unknown %s action %q", MaskTypeNamedGroups, action)
+		}
+	}
+
+	masked := make([]bool, len(value))
+	for _, match := range re.FindAllStringSubmatchIndex(value, -1) {
+		for _, i := range groups {
+			// the group did not participate in the match if its offsets are negative
+			for j := match[2*i]; j >= 0 && j < match[2*i+1]; j++ {
+				masked[j] = true
+			}
+		}
+	}
+
+	var sb strings.Builder
+	for i, r := range value {
+		if masked[i] {
+			sb.WriteString(m.maskCharFor(MaskTypeNamedGroups))
+		} else {
+			sb.WriteRune(r)
+		}
+	}
+
+	return sb.String(), nil
+}
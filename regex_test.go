@@ -0,0 +1,90 @@
+package mask
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	testPhoneRegex = regexp.MustCompile(`(?P<area>\d{2,4})-(?P<local>\d{2,4})-(?P<number>\d{4})`)
+	testUserRegex  = regexp.MustCompile(`(?P<user>\w+)@(?P<domain>[\w.]+)`)
+)
+
+func TestMaskNamedGroupsString(t *testing.T) {
+	type phoneTest struct {
+		Usagi string `mask:"namedgroups:phone:area=keep,number=mask"`
+	}
+	type phoneAllTest struct {
+		Usagi string `mask:"namedgroups:phone:local=mask,number=mask"`
+	}
+	type userTest struct {
+		Usagi []string `mask:"namedgroups:user:user=mask"`
+	}
+
+	tests := map[string]struct {
+		input any
+		want  any
+	}{
+		"mask a group": {
+			input: &phoneTest{Usagi: "03-1234-5678"},
+			want:  &phoneTest{Usagi: "03-1234-****"},
+		},
+		"mask groups": {
+			input: &phoneAllTest{Usagi: "03-1234-5678"},
+			want:  &phoneAllTest{Usagi: "03-****-****"},
+		},
+		"several matches in text": {
+			input: &phoneTest{Usagi: "ヤハッ！ 090-1111-2222 / 045-333-4444"},
+			want:  &phoneTest{Usagi: "ヤハッ！ 090-1111-**** / 045-333-****"},
+		},
+		"no match": {
+			input: &phoneTest{Usagi: "ハァ？"},
+			want:  &phoneTest{Usagi: "ハァ？"},
+		},
+		"slice": {
+			input: &userTest{Usagi: []string{"usagi@example.com", "ウラ"}},
+			want:  &userTest{Usagi: []string{"*****@example.com", "ウラ"}},
+		},
+		"zero string fields": {
+			input: &phoneTest{},
+			want:  &phoneTest{Usagi: ""},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(defaultTestCase(name), func(t *testing.T) {
+			cleanup(t)
+			RegisterMaskRegex("phone", testPhoneRegex)
+			RegisterMaskRegex("user", testUserRegex)
+			got, err := Mask(tt.input)
+			assert.Nil(t, err)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Error(diff)
+			}
+		})
+		t.Run(newMaskerTestCase(name), func(t *testing.T) {
+			m := newMasker()
+			m.RegisterMaskRegex("phone", testPhoneRegex)
+			m.RegisterMaskRegex("user", testUserRegex)
+			got, err := m.Mask(tt.input)
+			assert.Nil(t, err)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+
+	t.Run("errors", func(t *testing.T) {
+		m := newMasker()
+		m.RegisterMaskRegex("phone", testPhoneRegex)
+		_, err := m.MaskNamedGroupsString(":email:user=mask", "03-1234-5678")
+		assert.EqualError(t, err, `mask: regex "email" is not registered`)
+		_, err = m.MaskNamedGroupsString(":phone:country=mask", "03-1234-5678")
+		assert.EqualError(t, err, `mask: regex "phone" has no group "country"`)
+		_, err = m.MaskNamedGroupsString(":phone:area=hash", "03-1234-5678")
+		assert.EqualError(t, err, `mask: unknown namedgroups action "hash"`)
+	})
+}
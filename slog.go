@@ -0,0 +1,47 @@
+//go:build go1.21
+
+package mask
+
+import (
+	"log/slog"
+)
+
+// MaskLogValue returns a slog.Value holding the object with the mask applied
+// from default masker.
+func MaskLogValue(target any) slog.Value {
+	return defaultMasker.MaskLogValue(target)
+}
+
+// Masked returns a slog.LogValuer that masks the object only when a log record holding it is emitted
+// from default masker.
+func Masked(target any) MaskedValuer {
+	return defaultMasker.Masked(target)
+}
+
+// MaskLogValue returns a slog.Value holding the object with the mask applied.
+// If the object cannot be masked, the value is a string describing the error, so that the object is not logged as it is.
+func (m *Masker) MaskLogValue(target any) slog.Value {
+	v, err := m.Mask(target)
+	if err != nil {
+		return slog.StringValue("!ERROR: " + err.Error())
+	}
+
+	return slog.AnyValue(v)
+}
+
+// Masked returns a slog.LogValuer that masks the object only when a log record holding it is emitted.
+// For example, slog.Any("user", masker.Masked(user)) logs the masked user, and does not mask it if the log level is disabled.
+func (m *Masker) Masked(target any) MaskedValuer {
+	return MaskedValuer{masker: m, target: target}
+}
+
+// MaskedValuer is a slog.LogValuer that masks an object when it is logged. It is created by Masked.
+type MaskedValuer struct {
+	masker *Masker
+	target any
+}
+
+// LogValue returns the object with the mask applied.
+func (v MaskedValuer) LogValue() slog.Value {
+	return v.masker.MaskLogValue(v.target)
+}
@@ -0,0 +1,83 @@
+//go:build go1.21
+
+package mask
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaskLogValue(t *testing.T) {
+	type userTest struct {
+		Name  string `mask:"filled"`
+		Email string `mask:"fixed"`
+		Age   int
+	}
+	input := &userTest{Name: "ヤハッ！", Email: "usagi@example.com", Age: 3}
+	want := `{"level":"INFO","msg":"login","user":{"Name":"****","Email":"********","Age":3}}` + "\n"
+
+	newLogger := func(buf *bytes.Buffer) *slog.Logger {
+		return slog.New(slog.NewJSONHandler(buf, &slog.HandlerOptions{
+			ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+				if a.Key == slog.TimeKey && len(groups) == 0 {
+					return slog.Attr{}
+				}
+				return a
+			},
+		}))
+	}
+
+	t.Run(defaultTestCase("value"), func(t *testing.T) {
+		cleanup(t)
+		var buf bytes.Buffer
+		newLogger(&buf).Info("login", slog.Any("user", MaskLogValue(input)))
+		assert.Equal(t, want, buf.String())
+		assert.Equal(t, "ヤハッ！", input.Name)
+	})
+	t.Run(defaultTestCase("valuer"), func(t *testing.T) {
+		cleanup(t)
+		var buf bytes.Buffer
+		newLogger(&buf).Info("login", slog.Any("user", Masked(input)))
+		assert.Equal(t, want, buf.String())
+	})
+	t.Run(newMaskerTestCase("valuer"), func(t *testing.T) {
+		var buf bytes.Buffer
+		newLogger(&buf).Info("login", slog.Any("user", newMasker().Masked(input)))
+		assert.Equal(t, want, buf.String())
+	})
+	t.Run(newMaskerTestCase("not masked if the record is not emitted"), func(t *testing.T) {
+		m := newMasker()
+		called := 0
+		m.RegisterMaskStringFunc("count", func(arg, value string) (string, error) {
+			called++
+			return value, nil
+		})
+		type countTest struct {
+			Name string `mask:"count"`
+		}
+		var buf bytes.Buffer
+		logger := newLogger(&buf)
+		logger.Debug("login", slog.Any("user", m.Masked(countTest{Name: "ウラ"})))
+		assert.Equal(t, 0, called)
+		assert.Equal(t, "", buf.String())
+
+		logger.Log(context.Background(), slog.LevelInfo, "login", slog.Any("user", m.Masked(countTest{Name: "ウラ"})))
+		assert.Equal(t, 1, called)
+	})
+	t.Run(newMaskerTestCase("error"), func(t *testing.T) {
+		m := newMasker()
+		m.RegisterMaskStringFunc("err", func(arg, value string) (string, error) {
+			return "", errors.New("error")
+		})
+		type errTest struct {
+			Name string `mask:"err"`
+		}
+		v := m.MaskLogValue(errTest{Name: "ウラ"})
+		assert.Equal(t, slog.StringValue("!ERROR: error"), v)
+	})
+}
@@ -0,0 +1,56 @@
+package mask
+
+import (
+	"database/sql"
+	"reflect"
+	"time"
+)
+
+// registerSQLNullTypes registers the unwrappers of the Null types of database/sql,
+// so that the payload of a valid value is masked with the tag of the value, and an invalid value is kept as it is.
+func (m *Masker) registerSQLNullTypes() {
+	m.unwrapperMap[reflect.TypeOf(sql.NullString{})] = nullUnwrapper(
+		func(n sql.NullString) (string, bool) { return n.String, n.Valid },
+		func(v string) sql.NullString { return sql.NullString{String: v, Valid: true} },
+	)
+	m.unwrapperMap[reflect.TypeOf(sql.NullInt64{})] = nullUnwrapper(
+		func(n sql.NullInt64) (int64, bool) { return n.Int64, n.Valid },
+		func(v int64) sql.NullInt64 { return sql.NullInt64{Int64: v, Valid: true} },
+	)
+	m.unwrapperMap[reflect.TypeOf(sql.NullInt32{})] = nullUnwrapper(
+		func(n sql.NullInt32) (int32, bool) { return n.Int32, n.Valid },
+		func(v int32) sql.NullInt32 { return sql.NullInt32{Int32: v, Valid: true} },
+	)
+	m.unwrapperMap[reflect.TypeOf(sql.NullInt16{})] = nullUnwrapper(
+		func(n sql.NullInt16) (int16, bool) { return n.Int16, n.Valid },
+		func(v int16) sql.NullInt16 { return sql.NullInt16{Int16: v, Valid: true} },
+	)
+	m.unwrapperMap[reflect.TypeOf(sql.NullByte{})] = nullUnwrapper(
+		func(n sql.NullByte) (byte, bool) { return n.Byte, n.Valid },
+		func(v byte) sql.NullByte { return sql.NullByte{Byte: v, Valid: true} },
+	)
+	m.unwrapperMap[reflect.TypeOf(sql.NullFloat64{})] = nullUnwrapper(
+		func(n sql.NullFloat64) (float64, bool) { return n.Float64, n.Valid },
+		func(v float64) sql.NullFloat64 { return sql.NullFloat64{Float64: v, Valid: true} },
+	)
+	m.unwrapperMap[reflect.TypeOf(sql.NullBool{})] = nullUnwrapper(
+		func(n sql.NullBool) (bool, bool) { return n.Bool, n.Valid },
+		func(v bool) sql.NullBool { return sql.NullBool{Bool: v, Valid: true} },
+	)
+	m.unwrapperMap[reflect.TypeOf(sql.NullTime{})] = nullUnwrapper(
+		func(n sql.NullTime) (time.Time, bool) { return n.Time, n.Valid },
+		func(v time.Time) sql.NullTime { return sql.NullTime{Time: v, Valid: true} },
+	)
+}
+
+// nullUnwrapper returns an UnwrapFunc of a Null type of database/sql.
+func nullUnwrapper[N, T any](get func(N) (T, bool), wrap func(T) N) UnwrapFunc {
+	return func(value any) (any, func(any) any) {
+		n := value.(N)
+		payload, valid := get(n)
+		if !valid {
+			return nil, func(any) any { return n }
+		}
+		return payload, func(masked any) any { return wrap(masked.(T)) }
+	}
+}
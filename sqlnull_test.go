@@ -0,0 +1,106 @@
+package mask
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMask_SQLNullTypes(t *testing.T) {
+	type nullTest struct {
+		String   sql.NullString  `mask:"filled"`
+		Int64    sql.NullInt64   `mask:"zero"`
+		Int32    sql.NullInt32   `mask:"zero"`
+		Int16    sql.NullInt16   `mask:"zero"`
+		Byte     sql.NullByte    `mask:"zero"`
+		Float64  sql.NullFloat64 `mask:"zero"`
+		Bool     sql.NullBool    `mask:"zero"`
+		Time     sql.NullTime    `mask:"zero"`
+		Ptr      *sql.NullString `mask:"filled4"`
+		Untagged sql.NullString
+	}
+	type randomTest struct {
+		Int64 sql.NullInt64 `mask:"random10"`
+	}
+
+	now := time.Now()
+	tests := map[string]struct {
+		input any
+		want  any
+	}{
+		"valid values": {
+			input: &nullTest{
+				String:   sql.NullString{String: "ヤハッ！", Valid: true},
+				Int64:    sql.NullInt64{Int64: 64, Valid: true},
+				Int32:    sql.NullInt32{Int32: 32, Valid: true},
+				Int16:    sql.NullInt16{Int16: 16, Valid: true},
+				Byte:     sql.NullByte{Byte: 8, Valid: true},
+				Float64:  sql.NullFloat64{Float64: 1.5, Valid: true},
+				Bool:     sql.NullBool{Bool: true, Valid: true},
+				Time:     sql.NullTime{Time: now, Valid: true},
+				Ptr:      &sql.NullString{String: "ウラ", Valid: true},
+				Untagged: sql.NullString{String: "フゥン", Valid: true},
+			},
+			want: &nullTest{
+				String:   sql.NullString{String: "****", Valid: true},
+				Int64:    sql.NullInt64{Valid: true},
+				Int32:    sql.NullInt32{Valid: true},
+				Int16:    sql.NullInt16{Valid: true},
+				Byte:     sql.NullByte{Valid: true},
+				Float64:  sql.NullFloat64{Valid: true},
+				Bool:     sql.NullBool{Valid: true},
+				Time:     sql.NullTime{Valid: true},
+				Ptr:      &sql.NullString{String: "****", Valid: true},
+				Untagged: sql.NullString{String: "フゥン", Valid: true},
+			},
+		},
+		"invalid values": {
+			input: &nullTest{
+				String: sql.NullString{String: "ヤハッ！"},
+				Int64:  sql.NullInt64{Int64: 64},
+				Time:   sql.NullTime{Time: now},
+				Ptr:    &sql.NullString{},
+			},
+			want: &nullTest{
+				String: sql.NullString{String: "ヤハッ！"},
+				Int64:  sql.NullInt64{Int64: 64},
+				Time:   sql.NullTime{Time: now},
+				Ptr:    &sql.NullString{},
+			},
+		},
+		"nil ptr": {
+			input: &nullTest{String: sql.NullString{String: "ハァ？", Valid: true}},
+			want:  &nullTest{String: sql.NullString{String: "***", Valid: true}},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(defaultTestCase(name), func(t *testing.T) {
+			cleanup(t)
+			got, err := Mask(tt.input)
+			assert.Nil(t, err)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Error(diff)
+			}
+		})
+		t.Run(newMaskerTestCase(name), func(t *testing.T) {
+			m := newMasker()
+			got, err := m.Mask(tt.input)
+			assert.Nil(t, err)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+
+	t.Run(newMaskerTestCase("random int"), func(t *testing.T) {
+		m := newMasker()
+		got, err := MaskTypedWith(m, &randomTest{Int64: sql.NullInt64{Int64: 12345, Valid: true}})
+		assert.Nil(t, err)
+		assert.True(t, got.Int64.Valid)
+		assert.Less(t, got.Int64.Int64, int64(10))
+	})
+}